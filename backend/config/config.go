@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"time"
@@ -8,18 +9,69 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Config fields all carry both yaml and json tags: yaml for config.yaml,
+// json so GET /api/v1/config (handlers.ConfigHandler) can return the
+// effective config verbatim instead of a hand-maintained admin DTO.
 type Config struct {
-	Device DeviceConfig `yaml:"device"`
-	Game   GameConfig   `yaml:"game"`
+	Device    DeviceConfig    `yaml:"device" json:"device"`
+	Game      GameConfig      `yaml:"game" json:"game"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" json:"rate_limit"`
+	Entropy   EntropyConfig   `yaml:"entropy" json:"entropy"`
+	Redis     RedisConfig     `yaml:"redis" json:"redis"`
+	Replay    ReplayConfig    `yaml:"replay" json:"replay"`
+	Store     StoreConfig     `yaml:"store" json:"store"`
 }
 
 type DeviceConfig struct {
-	OfflineTimeout int `yaml:"offline_timeout"`
+	OfflineTimeout int `yaml:"offline_timeout" json:"offline_timeout"`
 }
 
 type GameConfig struct {
-	DefaultTimeLimit int `yaml:"default_time_limit"`
-	DefaultStrikes   int `yaml:"default_strikes"`
+	DefaultTimeLimit int `yaml:"default_time_limit" json:"default_time_limit"`
+	DefaultStrikes   int `yaml:"default_strikes" json:"default_strikes"`
+}
+
+// RateLimitConfig tunes the global HTTP rate limiter and the per-game action
+// throttle so ops can adjust them without a redeploy.
+type RateLimitConfig struct {
+	GlobalRPS       float64 `yaml:"global_rps" json:"global_rps"`               // requests/sec allowed across all of /api/v1/*
+	GlobalBurst     int     `yaml:"global_burst" json:"global_burst"`           // burst allowance on top of GlobalRPS
+	GameActionRPS   float64 `yaml:"game_action_rps" json:"game_action_rps"`     // actions/sec allowed per game in ProcessAction
+	GameActionBurst int     `yaml:"game_action_burst" json:"game_action_burst"` // burst allowance on top of GameActionRPS
+}
+
+// EntropyConfig tunes how submitted sample batches are accepted into the
+// pool and how strict the SP 800-90B continuous health tests are.
+type EntropyConfig struct {
+	MaxPoolSize          int     `yaml:"max_pool_size" json:"max_pool_size"`                 // byte capacity of the Fortuna-style accumulator pool
+	MinSamples           int     `yaml:"min_samples" json:"min_samples"`                     // minimum batch size accepted by Submit
+	MinQuality           float64 `yaml:"min_quality" json:"min_quality"`                     // minimum Verify quality score to pool a batch
+	MinEntropy           float64 `yaml:"min_entropy" json:"min_entropy"`                     // assumed per-sample min-entropy (bits), drives health test cutoffs
+	SeedPath             string  `yaml:"seed_path" json:"seed_path"`                         // sealed file the pool's generator state persists across restarts
+	ExtractorSeedPath    string  `yaml:"extractor_seed_path" json:"extractor_seed_path"`     // long-lived Toeplitz matrix seed, persisted across restarts
+	ExtractorCompression float64 `yaml:"extractor_compression" json:"extractor_compression"` // safety margin in (0, 1] applied to the min-entropy estimate before extraction
+}
+
+// RedisConfig toggles the Redis-backed pub/sub ws.Broker used for
+// horizontal scaling. The connection URL itself comes from the REDIS_URL
+// environment variable, not config.yaml, since it's effectively a
+// credential.
+type RedisConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"` // if true, main.go wires ws.NewRedisBroker(env.RedisURL) instead of the in-memory default
+}
+
+// ReplayConfig points game.Engine.SetReplayDir at where finished games'
+// gzip-compressed NDJSON event logs are written.
+type ReplayConfig struct {
+	Dir string `yaml:"dir" json:"dir"`
+}
+
+// StoreConfig selects the game.Store backend game.Engine persists live
+// games to. Backend must be "memory" (which doesn't survive a restart),
+// "bolt" (DefaultConfig's choice), or "sqlite"; Path is ignored for "memory".
+type StoreConfig struct {
+	Backend string `yaml:"backend" json:"backend"`
+	Path    string `yaml:"path" json:"path"`
 }
 
 type Env struct {
@@ -27,34 +79,148 @@ type Env struct {
 	ServerHost   string
 	ServerPort   int
 	GinMode      string
+	RedisURL     string
+	EnablePprof  bool
 }
 
-func Load(path string) (*Config, error) {
-	cfg := &Config{
-		Device: DeviceConfig{
-			OfflineTimeout: 120,
-		},
-		Game: GameConfig{
-			DefaultTimeLimit: 300,
-			DefaultStrikes:   3,
-		},
+// Load reads path (falling back to built-in defaults if it doesn't exist),
+// applies PHOTON_* environment overrides, validates the result, and wraps
+// it in a Watcher that WatchSIGHUP can later reload in place. Everything
+// downstream (game.Engine, the HTTP handlers) should hold onto the Watcher
+// rather than a single *Config, so a reload is visible everywhere at once.
+func Load(path string) (*Watcher, error) {
+	cfg, err := loadFile(path)
+	if err != nil {
+		return nil, err
 	}
+	return newWatcher(path, cfg), nil
+}
+
+// loadFile reads and validates a single *Config snapshot from path, without
+// wrapping it in a Watcher. Used by Load and by Watcher.Reload.
+func loadFile(path string) (*Config, error) {
+	cfg := DefaultConfig()
 
 	data, err := os.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
+		if !os.IsNotExist(err) {
+			return nil, err
 		}
+	} else if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, err
 	}
 
-	if err := yaml.Unmarshal(data, cfg); err != nil {
+	applyEnvOverrides(cfg)
+
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 
 	return cfg, nil
 }
 
+// Validate rejects settings that would leave the game engine or device
+// presence tracking in a nonsensical state (e.g. a game that times out
+// immediately, or a device considered offline the instant it connects).
+func (c *Config) Validate() error {
+	if c.Game.DefaultTimeLimit <= 0 {
+		return fmt.Errorf("game.default_time_limit must be positive, got %d", c.Game.DefaultTimeLimit)
+	}
+	if c.Game.DefaultStrikes <= 0 {
+		return fmt.Errorf("game.default_strikes must be positive, got %d", c.Game.DefaultStrikes)
+	}
+	if c.Device.OfflineTimeout <= 0 {
+		return fmt.Errorf("device.offline_timeout must be positive, got %d", c.Device.OfflineTimeout)
+	}
+	if c.Entropy.MaxPoolSize <= 0 {
+		return fmt.Errorf("entropy.max_pool_size must be positive, got %d", c.Entropy.MaxPoolSize)
+	}
+	switch c.Store.Backend {
+	case "memory", "bolt", "sqlite":
+	default:
+		return fmt.Errorf("store.backend must be memory, bolt, or sqlite, got %q", c.Store.Backend)
+	}
+	return nil
+}
+
+// envOverrides maps a PHOTON_<SECTION>_<FIELD> environment variable name to
+// a setter applied after the YAML file (or its defaults) has been loaded,
+// so ops can override any individual key without editing config.yaml.
+func envOverrides(cfg *Config) map[string]func(string) error {
+	return map[string]func(string) error{
+		"PHOTON_DEVICE_OFFLINE_TIMEOUT":        intSetter(&cfg.Device.OfflineTimeout),
+		"PHOTON_GAME_DEFAULT_TIME_LIMIT":       intSetter(&cfg.Game.DefaultTimeLimit),
+		"PHOTON_GAME_DEFAULT_STRIKES":          intSetter(&cfg.Game.DefaultStrikes),
+		"PHOTON_RATE_LIMIT_GLOBAL_RPS":         floatSetter(&cfg.RateLimit.GlobalRPS),
+		"PHOTON_RATE_LIMIT_GLOBAL_BURST":       intSetter(&cfg.RateLimit.GlobalBurst),
+		"PHOTON_RATE_LIMIT_GAME_ACTION_RPS":    floatSetter(&cfg.RateLimit.GameActionRPS),
+		"PHOTON_RATE_LIMIT_GAME_ACTION_BURST":  intSetter(&cfg.RateLimit.GameActionBurst),
+		"PHOTON_ENTROPY_MAX_POOL_SIZE":         intSetter(&cfg.Entropy.MaxPoolSize),
+		"PHOTON_ENTROPY_MIN_SAMPLES":           intSetter(&cfg.Entropy.MinSamples),
+		"PHOTON_ENTROPY_MIN_QUALITY":           floatSetter(&cfg.Entropy.MinQuality),
+		"PHOTON_ENTROPY_MIN_ENTROPY":           floatSetter(&cfg.Entropy.MinEntropy),
+		"PHOTON_ENTROPY_SEED_PATH":             stringSetter(&cfg.Entropy.SeedPath),
+		"PHOTON_ENTROPY_EXTRACTOR_SEED_PATH":   stringSetter(&cfg.Entropy.ExtractorSeedPath),
+		"PHOTON_ENTROPY_EXTRACTOR_COMPRESSION": floatSetter(&cfg.Entropy.ExtractorCompression),
+		"PHOTON_REDIS_ENABLED":                 boolSetter(&cfg.Redis.Enabled),
+		"PHOTON_REPLAY_DIR":                    stringSetter(&cfg.Replay.Dir),
+		"PHOTON_STORE_BACKEND":                 stringSetter(&cfg.Store.Backend),
+		"PHOTON_STORE_PATH":                    stringSetter(&cfg.Store.Path),
+	}
+}
+
+// applyEnvOverrides mutates cfg in place for every PHOTON_* variable that's
+// set in the environment. Unset variables leave the YAML-loaded value
+// untouched; malformed values are ignored so a typo'd override can't take
+// the whole config down.
+func applyEnvOverrides(cfg *Config) {
+	for name, set := range envOverrides(cfg) {
+		if v := os.Getenv(name); v != "" {
+			_ = set(v)
+		}
+	}
+}
+
+func intSetter(dst *int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*dst = n
+		return nil
+	}
+}
+
+func floatSetter(dst *float64) func(string) error {
+	return func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*dst = f
+		return nil
+	}
+}
+
+func boolSetter(dst *bool) func(string) error {
+	return func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*dst = b
+		return nil
+	}
+}
+
+func stringSetter(dst *string) func(string) error {
+	return func(v string) error {
+		*dst = v
+		return nil
+	}
+}
+
 func LoadEnv() *Env {
 	port := 8080
 	if v := os.Getenv("SERVER_PORT"); v != "" {
@@ -63,11 +229,15 @@ func LoadEnv() *Env {
 		}
 	}
 
+	enablePprof, _ := strconv.ParseBool(getEnvOrDefault("ENABLE_PPROF", "false"))
+
 	return &Env{
 		DatabasePath: getEnvOrDefault("DATABASE_PATH", "./data/photon.db"),
 		ServerHost:   getEnvOrDefault("SERVER_HOST", "0.0.0.0"),
 		ServerPort:   port,
 		GinMode:      getEnvOrDefault("GIN_MODE", "debug"),
+		RedisURL:     getEnvOrDefault("REDIS_URL", "redis://localhost:6379/0"),
+		EnablePprof:  enablePprof,
 	}
 }
 
@@ -96,5 +266,30 @@ func DefaultConfig() *Config {
 			DefaultTimeLimit: 300,
 			DefaultStrikes:   3,
 		},
+		RateLimit: RateLimitConfig{
+			GlobalRPS:       50,
+			GlobalBurst:     100,
+			GameActionRPS:   5,
+			GameActionBurst: 10,
+		},
+		Entropy: EntropyConfig{
+			MaxPoolSize:          1 << 20,
+			MinSamples:           32,
+			MinQuality:           0.7,
+			MinEntropy:           0.5,
+			SeedPath:             "./data/entropy.seed",
+			ExtractorSeedPath:    "./data/extractor.seed",
+			ExtractorCompression: 0.9,
+		},
+		Redis: RedisConfig{
+			Enabled: false,
+		},
+		Replay: ReplayConfig{
+			Dir: "./data/replays",
+		},
+		Store: StoreConfig{
+			Backend: "bolt",
+			Path:    "./data/games.db",
+		},
 	}
 }
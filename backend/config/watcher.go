@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Watcher holds the live, effective Config and lets it be swapped out in
+// place when config.yaml changes, so long-lived consumers (game.Engine, the
+// HTTP handlers) always read the current settings instead of a value
+// captured once at startup.
+type Watcher struct {
+	mu   sync.RWMutex
+	cfg  *Config
+	path string
+
+	// OnReloadError, if set, is called whenever WatchSIGHUP's reload fails
+	// (bad YAML, a failed Validate). The previously loaded Config is kept
+	// in that case, so a bad edit to config.yaml can't take the server down.
+	OnReloadError func(error)
+}
+
+func newWatcher(path string, cfg *Config) *Watcher {
+	return &Watcher{path: path, cfg: cfg}
+}
+
+// NewWatcher wraps an already-loaded Config, for callers (like main.go's
+// fallback-to-defaults path) that didn't go through Load.
+func NewWatcher(cfg *Config) *Watcher {
+	return &Watcher{cfg: cfg}
+}
+
+// Get returns the current effective Config. Callers must not mutate it.
+func (w *Watcher) Get() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cfg
+}
+
+// Reload re-reads the file the Watcher was created from, applying env
+// overrides and Validate the same way Load does, and atomically swaps it in
+// on success. If path is empty (the Watcher wasn't created via Load) or the
+// reload fails, the previously loaded Config is left in place.
+func (w *Watcher) Reload() error {
+	if w.path == "" {
+		return nil
+	}
+
+	cfg, err := loadFile(w.path)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.cfg = cfg
+	w.mu.Unlock()
+	return nil
+}
+
+// WatchSIGHUP starts a goroutine that reloads the config on SIGHUP, the
+// conventional signal for "re-read your config file" (sent by e.g. `kill
+// -HUP` or a systemd ExecReload). It returns immediately; the goroutine
+// runs for the lifetime of the process.
+func (w *Watcher) WatchSIGHUP() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+
+	go func() {
+		for range ch {
+			if err := w.Reload(); err != nil && w.OnReloadError != nil {
+				w.OnReloadError(err)
+			}
+		}
+	}()
+}
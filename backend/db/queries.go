@@ -0,0 +1,483 @@
+// Package db implements the device/entropy/stats persistence queries by
+// hand against plain database/sql, against the devices/device_keys/commits/
+// game_actions tables created by db/migrations/00002_entropy_devices.sql.
+// It's the same repository-interface alternative to a generated sqlc
+// package that game.SQLHistoryStore already is for match history: same
+// role, no dependency on generated code that isn't checked into this repo.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Queries wraps the *sql.DB handle every method runs its statement
+// against.
+type Queries struct {
+	db *sql.DB
+}
+
+// New returns a Queries bound to conn. conn is typically the same handle
+// main.go already ran goose migrations against.
+func New(conn *sql.DB) *Queries {
+	return &Queries{db: conn}
+}
+
+// Device is a row from the devices table.
+type Device struct {
+	ID             string
+	LastSeen       sql.NullTime
+	TotalCommits   sql.NullInt64
+	AverageQuality sql.NullFloat64
+	IsTooBright    sql.NullInt64
+	IsSuspect      sql.NullInt64
+}
+
+// DeviceKey is a row from the device_keys table.
+type DeviceKey struct {
+	DeviceID  string
+	PublicKey string
+	Secret    string
+	CreatedAt sql.NullTime
+}
+
+// Commit is a row from the commits table.
+type Commit struct {
+	ID                  string
+	DeviceID            string
+	RawSamples          []byte
+	Timestamps          []byte
+	Quality             float64
+	PreExtractionBytes  int64
+	PostExtractionBytes int64
+	DebiasedBits        int64
+
+	TestFrequencyPassed int64
+	TestFrequencyRatio  sql.NullFloat64
+	TestRunsPassed      int64
+	TestRunsMaxLength   sql.NullInt64
+	TestChiPassed       int64
+	TestChiValue        sql.NullFloat64
+	TestVariancePassed  int64
+	TestVarianceValue   sql.NullFloat64
+
+	TestMonobitFrequencyPassed  int64
+	TestMonobitFrequencyPValue  sql.NullFloat64
+	TestBlockFrequencyPassed    int64
+	TestBlockFrequencyPValue    sql.NullFloat64
+	TestConditionedRunsPassed   int64
+	TestConditionedRunsPValue   sql.NullFloat64
+	TestLongestRunPassed        int64
+	TestLongestRunPValue        sql.NullFloat64
+	TestCumulativeSumsFwdPassed int64
+	TestCumulativeSumsFwdPValue sql.NullFloat64
+	TestCumulativeSumsBwdPassed int64
+	TestCumulativeSumsBwdPValue sql.NullFloat64
+	TestApproxEntropyM2Passed   int64
+	TestApproxEntropyM2PValue   sql.NullFloat64
+	TestApproxEntropyM3Passed   int64
+	TestApproxEntropyM3PValue   sql.NullFloat64
+
+	CreatedAt sql.NullTime
+}
+
+// GameAction is a row from the game_actions table.
+type GameAction struct {
+	ID        int64
+	GameID    string
+	Seq       int64
+	ModuleID  string
+	Action    string
+	Success   bool
+	Strike    bool
+	Solved    bool
+	CreatedAt sql.NullTime
+}
+
+// CreateCommitParams holds CreateCommit's insert values.
+type CreateCommitParams struct {
+	ID                  string
+	DeviceID            string
+	RawSamples          []byte
+	Timestamps          []byte
+	Quality             float64
+	PreExtractionBytes  int64
+	PostExtractionBytes int64
+	DebiasedBits        int64
+
+	TestFrequencyPassed int64
+	TestFrequencyRatio  sql.NullFloat64
+	TestRunsPassed      int64
+	TestRunsMaxLength   sql.NullInt64
+	TestChiPassed       int64
+	TestChiValue        sql.NullFloat64
+	TestVariancePassed  int64
+	TestVarianceValue   sql.NullFloat64
+
+	TestMonobitFrequencyPassed  int64
+	TestMonobitFrequencyPValue  sql.NullFloat64
+	TestBlockFrequencyPassed    int64
+	TestBlockFrequencyPValue    sql.NullFloat64
+	TestConditionedRunsPassed   int64
+	TestConditionedRunsPValue   sql.NullFloat64
+	TestLongestRunPassed        int64
+	TestLongestRunPValue        sql.NullFloat64
+	TestCumulativeSumsFwdPassed int64
+	TestCumulativeSumsFwdPValue sql.NullFloat64
+	TestCumulativeSumsBwdPassed int64
+	TestCumulativeSumsBwdPValue sql.NullFloat64
+	TestApproxEntropyM2Passed   int64
+	TestApproxEntropyM2PValue   sql.NullFloat64
+	TestApproxEntropyM3Passed   int64
+	TestApproxEntropyM3PValue   sql.NullFloat64
+}
+
+// CreateCommit inserts one entropy commit row.
+func (q *Queries) CreateCommit(ctx context.Context, p CreateCommitParams) (Commit, error) {
+	_, err := q.db.ExecContext(ctx, `
+		INSERT INTO commits (
+			id, device_id, raw_samples, timestamps, quality,
+			pre_extraction_bytes, post_extraction_bytes, debiased_bits,
+			test_frequency_passed, test_frequency_ratio,
+			test_runs_passed, test_runs_max_length,
+			test_chi_passed, test_chi_value,
+			test_variance_passed, test_variance_value,
+			test_monobit_frequency_passed, test_monobit_frequency_pvalue,
+			test_block_frequency_passed, test_block_frequency_pvalue,
+			test_conditioned_runs_passed, test_conditioned_runs_pvalue,
+			test_longest_run_passed, test_longest_run_pvalue,
+			test_cumulative_sums_fwd_passed, test_cumulative_sums_fwd_pvalue,
+			test_cumulative_sums_bwd_passed, test_cumulative_sums_bwd_pvalue,
+			test_approx_entropy_m2_passed, test_approx_entropy_m2_pvalue,
+			test_approx_entropy_m3_passed, test_approx_entropy_m3_pvalue
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.DeviceID, p.RawSamples, p.Timestamps, p.Quality,
+		p.PreExtractionBytes, p.PostExtractionBytes, p.DebiasedBits,
+		p.TestFrequencyPassed, p.TestFrequencyRatio,
+		p.TestRunsPassed, p.TestRunsMaxLength,
+		p.TestChiPassed, p.TestChiValue,
+		p.TestVariancePassed, p.TestVarianceValue,
+		p.TestMonobitFrequencyPassed, p.TestMonobitFrequencyPValue,
+		p.TestBlockFrequencyPassed, p.TestBlockFrequencyPValue,
+		p.TestConditionedRunsPassed, p.TestConditionedRunsPValue,
+		p.TestLongestRunPassed, p.TestLongestRunPValue,
+		p.TestCumulativeSumsFwdPassed, p.TestCumulativeSumsFwdPValue,
+		p.TestCumulativeSumsBwdPassed, p.TestCumulativeSumsBwdPValue,
+		p.TestApproxEntropyM2Passed, p.TestApproxEntropyM2PValue,
+		p.TestApproxEntropyM3Passed, p.TestApproxEntropyM3PValue,
+	)
+	if err != nil {
+		return Commit{}, fmt.Errorf("create commit: %w", err)
+	}
+
+	return Commit{
+		ID:                          p.ID,
+		DeviceID:                    p.DeviceID,
+		RawSamples:                  p.RawSamples,
+		Timestamps:                  p.Timestamps,
+		Quality:                     p.Quality,
+		PreExtractionBytes:          p.PreExtractionBytes,
+		PostExtractionBytes:         p.PostExtractionBytes,
+		DebiasedBits:                p.DebiasedBits,
+		TestFrequencyPassed:         p.TestFrequencyPassed,
+		TestFrequencyRatio:          p.TestFrequencyRatio,
+		TestRunsPassed:              p.TestRunsPassed,
+		TestRunsMaxLength:           p.TestRunsMaxLength,
+		TestChiPassed:               p.TestChiPassed,
+		TestChiValue:                p.TestChiValue,
+		TestVariancePassed:          p.TestVariancePassed,
+		TestVarianceValue:           p.TestVarianceValue,
+		TestMonobitFrequencyPassed:  p.TestMonobitFrequencyPassed,
+		TestMonobitFrequencyPValue:  p.TestMonobitFrequencyPValue,
+		TestBlockFrequencyPassed:    p.TestBlockFrequencyPassed,
+		TestBlockFrequencyPValue:    p.TestBlockFrequencyPValue,
+		TestConditionedRunsPassed:   p.TestConditionedRunsPassed,
+		TestConditionedRunsPValue:   p.TestConditionedRunsPValue,
+		TestLongestRunPassed:        p.TestLongestRunPassed,
+		TestLongestRunPValue:        p.TestLongestRunPValue,
+		TestCumulativeSumsFwdPassed: p.TestCumulativeSumsFwdPassed,
+		TestCumulativeSumsFwdPValue: p.TestCumulativeSumsFwdPValue,
+		TestCumulativeSumsBwdPassed: p.TestCumulativeSumsBwdPassed,
+		TestCumulativeSumsBwdPValue: p.TestCumulativeSumsBwdPValue,
+		TestApproxEntropyM2Passed:   p.TestApproxEntropyM2Passed,
+		TestApproxEntropyM2PValue:   p.TestApproxEntropyM2PValue,
+		TestApproxEntropyM3Passed:   p.TestApproxEntropyM3Passed,
+		TestApproxEntropyM3PValue:   p.TestApproxEntropyM3PValue,
+	}, nil
+}
+
+const commitColumns = `id, device_id, raw_samples, timestamps, quality,
+	pre_extraction_bytes, post_extraction_bytes, debiased_bits,
+	test_frequency_passed, test_frequency_ratio,
+	test_runs_passed, test_runs_max_length,
+	test_chi_passed, test_chi_value,
+	test_variance_passed, test_variance_value,
+	test_monobit_frequency_passed, test_monobit_frequency_pvalue,
+	test_block_frequency_passed, test_block_frequency_pvalue,
+	test_conditioned_runs_passed, test_conditioned_runs_pvalue,
+	test_longest_run_passed, test_longest_run_pvalue,
+	test_cumulative_sums_fwd_passed, test_cumulative_sums_fwd_pvalue,
+	test_cumulative_sums_bwd_passed, test_cumulative_sums_bwd_pvalue,
+	test_approx_entropy_m2_passed, test_approx_entropy_m2_pvalue,
+	test_approx_entropy_m3_passed, test_approx_entropy_m3_pvalue,
+	created_at`
+
+func scanCommit(row interface {
+	Scan(dest ...interface{}) error
+}) (Commit, error) {
+	var c Commit
+	err := row.Scan(
+		&c.ID, &c.DeviceID, &c.RawSamples, &c.Timestamps, &c.Quality,
+		&c.PreExtractionBytes, &c.PostExtractionBytes, &c.DebiasedBits,
+		&c.TestFrequencyPassed, &c.TestFrequencyRatio,
+		&c.TestRunsPassed, &c.TestRunsMaxLength,
+		&c.TestChiPassed, &c.TestChiValue,
+		&c.TestVariancePassed, &c.TestVarianceValue,
+		&c.TestMonobitFrequencyPassed, &c.TestMonobitFrequencyPValue,
+		&c.TestBlockFrequencyPassed, &c.TestBlockFrequencyPValue,
+		&c.TestConditionedRunsPassed, &c.TestConditionedRunsPValue,
+		&c.TestLongestRunPassed, &c.TestLongestRunPValue,
+		&c.TestCumulativeSumsFwdPassed, &c.TestCumulativeSumsFwdPValue,
+		&c.TestCumulativeSumsBwdPassed, &c.TestCumulativeSumsBwdPValue,
+		&c.TestApproxEntropyM2Passed, &c.TestApproxEntropyM2PValue,
+		&c.TestApproxEntropyM3Passed, &c.TestApproxEntropyM3PValue,
+		&c.CreatedAt,
+	)
+	return c, err
+}
+
+// GetCommitsByDeviceParams holds GetCommitsByDevice's filter/limit.
+type GetCommitsByDeviceParams struct {
+	DeviceID string
+	Limit    int64
+}
+
+// GetCommitsByDevice returns deviceID's most recent commits, newest first.
+func (q *Queries) GetCommitsByDevice(ctx context.Context, p GetCommitsByDeviceParams) ([]Commit, error) {
+	rows, err := q.db.QueryContext(ctx,
+		`SELECT `+commitColumns+` FROM commits WHERE device_id = ? ORDER BY created_at DESC LIMIT ?`,
+		p.DeviceID, p.Limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get commits by device: %w", err)
+	}
+	defer rows.Close()
+
+	var commits []Commit
+	for rows.Next() {
+		c, err := scanCommit(rows)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
+
+// GetRecentCommits returns the limit most recent commits across every
+// device, newest first.
+func (q *Queries) GetRecentCommits(ctx context.Context, limit int64) ([]Commit, error) {
+	rows, err := q.db.QueryContext(ctx,
+		`SELECT `+commitColumns+` FROM commits ORDER BY created_at DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get recent commits: %w", err)
+	}
+	defer rows.Close()
+
+	var commits []Commit
+	for rows.Next() {
+		c, err := scanCommit(rows)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
+
+// CountCommitsByDevice returns how many commits deviceID has submitted.
+func (q *Queries) CountCommitsByDevice(ctx context.Context, deviceID string) (int64, error) {
+	var count int64
+	err := q.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM commits WHERE device_id = ?`, deviceID).Scan(&count)
+	return count, err
+}
+
+// GetAverageQualityByDevice returns deviceID's mean commit quality, or 0 if
+// it has none yet.
+func (q *Queries) GetAverageQualityByDevice(ctx context.Context, deviceID string) (float64, error) {
+	var avg sql.NullFloat64
+	err := q.db.QueryRowContext(ctx, `SELECT AVG(quality) FROM commits WHERE device_id = ?`, deviceID).Scan(&avg)
+	if err != nil {
+		return 0, err
+	}
+	return avg.Float64, nil
+}
+
+// CountDevices returns the total number of known devices.
+func (q *Queries) CountDevices(ctx context.Context) (int64, error) {
+	var count int64
+	err := q.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM devices`).Scan(&count)
+	return count, err
+}
+
+// CountTotalCommits returns the total number of commits across every
+// device.
+func (q *Queries) CountTotalCommits(ctx context.Context) (int64, error) {
+	var count int64
+	err := q.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM commits`).Scan(&count)
+	return count, err
+}
+
+// GetTotalSamplesCount returns the total number of raw samples accepted
+// across every commit (each sample encoded as 4 bytes, see
+// handlers.encodeIntSlice).
+func (q *Queries) GetTotalSamplesCount(ctx context.Context) (int64, error) {
+	var totalBytes sql.NullInt64
+	err := q.db.QueryRowContext(ctx, `SELECT SUM(LENGTH(raw_samples)) FROM commits`).Scan(&totalBytes)
+	if err != nil {
+		return 0, err
+	}
+	return totalBytes.Int64 / 4, nil
+}
+
+// UpsertDeviceParams holds UpsertDevice's insert/update values.
+type UpsertDeviceParams struct {
+	ID             string
+	LastSeen       sql.NullTime
+	TotalCommits   sql.NullInt64
+	AverageQuality sql.NullFloat64
+	IsTooBright    sql.NullInt64
+	IsSuspect      sql.NullInt64
+}
+
+// UpsertDevice inserts or updates a device's rolling status row.
+func (q *Queries) UpsertDevice(ctx context.Context, p UpsertDeviceParams) (Device, error) {
+	_, err := q.db.ExecContext(ctx, `
+		INSERT INTO devices (id, last_seen, total_commits, average_quality, is_too_bright, is_suspect)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			last_seen       = excluded.last_seen,
+			total_commits   = excluded.total_commits,
+			average_quality = excluded.average_quality,
+			is_too_bright   = excluded.is_too_bright,
+			is_suspect      = excluded.is_suspect`,
+		p.ID, p.LastSeen, p.TotalCommits, p.AverageQuality, p.IsTooBright, p.IsSuspect,
+	)
+	if err != nil {
+		return Device{}, fmt.Errorf("upsert device: %w", err)
+	}
+
+	return Device{
+		ID:             p.ID,
+		LastSeen:       p.LastSeen,
+		TotalCommits:   p.TotalCommits,
+		AverageQuality: p.AverageQuality,
+		IsTooBright:    p.IsTooBright,
+		IsSuspect:      p.IsSuspect,
+	}, nil
+}
+
+// GetDevice returns one device's current status row.
+func (q *Queries) GetDevice(ctx context.Context, deviceID string) (Device, error) {
+	var d Device
+	err := q.db.QueryRowContext(ctx,
+		`SELECT id, last_seen, total_commits, average_quality, is_too_bright, is_suspect FROM devices WHERE id = ?`,
+		deviceID,
+	).Scan(&d.ID, &d.LastSeen, &d.TotalCommits, &d.AverageQuality, &d.IsTooBright, &d.IsSuspect)
+	return d, err
+}
+
+// GetAllDevices returns every known device's current status row.
+func (q *Queries) GetAllDevices(ctx context.Context) ([]Device, error) {
+	rows, err := q.db.QueryContext(ctx,
+		`SELECT id, last_seen, total_commits, average_quality, is_too_bright, is_suspect FROM devices`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get all devices: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []Device
+	for rows.Next() {
+		var d Device
+		if err := rows.Scan(&d.ID, &d.LastSeen, &d.TotalCommits, &d.AverageQuality, &d.IsTooBright, &d.IsSuspect); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// CreateDeviceKeyParams holds CreateDeviceKey's insert values.
+type CreateDeviceKeyParams struct {
+	DeviceID  string
+	PublicKey string
+	Secret    string
+}
+
+// CreateDeviceKey registers a device's RSA public key and hands-back
+// secret, replacing any prior registration for the same device_id.
+func (q *Queries) CreateDeviceKey(ctx context.Context, p CreateDeviceKeyParams) (DeviceKey, error) {
+	_, err := q.db.ExecContext(ctx, `
+		INSERT INTO device_keys (device_id, public_key, secret)
+		VALUES (?, ?, ?)
+		ON CONFLICT(device_id) DO UPDATE SET
+			public_key = excluded.public_key,
+			secret     = excluded.secret`,
+		p.DeviceID, p.PublicKey, p.Secret,
+	)
+	if err != nil {
+		return DeviceKey{}, fmt.Errorf("create device key: %w", err)
+	}
+
+	return DeviceKey{DeviceID: p.DeviceID, PublicKey: p.PublicKey, Secret: p.Secret}, nil
+}
+
+// GetDeviceKey returns deviceID's registered public key and secret.
+func (q *Queries) GetDeviceKey(ctx context.Context, deviceID string) (DeviceKey, error) {
+	var k DeviceKey
+	err := q.db.QueryRowContext(ctx,
+		`SELECT device_id, public_key, secret, created_at FROM device_keys WHERE device_id = ?`,
+		deviceID,
+	).Scan(&k.DeviceID, &k.PublicKey, &k.Secret, &k.CreatedAt)
+	return k, err
+}
+
+// CreateGameActionParams holds CreateGameAction's insert values.
+type CreateGameActionParams struct {
+	GameID    string
+	Seq       int64
+	ModuleID  string
+	Action    string
+	Success   bool
+	Strike    bool
+	Solved    bool
+	CreatedAt time.Time
+}
+
+// CreateGameAction persists one processed game action, so it survives a
+// server restart for post-restart replay.
+func (q *Queries) CreateGameAction(ctx context.Context, p CreateGameActionParams) (GameAction, error) {
+	_, err := q.db.ExecContext(ctx, `
+		INSERT INTO game_actions (game_id, seq, module_id, action, success, strike, solved, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.GameID, p.Seq, p.ModuleID, p.Action, p.Success, p.Strike, p.Solved, p.CreatedAt,
+	)
+	if err != nil {
+		return GameAction{}, fmt.Errorf("create game action: %w", err)
+	}
+
+	return GameAction{
+		GameID:   p.GameID,
+		Seq:      p.Seq,
+		ModuleID: p.ModuleID,
+		Action:   p.Action,
+		Success:  p.Success,
+		Strike:   p.Strike,
+		Solved:   p.Solved,
+	}, nil
+}
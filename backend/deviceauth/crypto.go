@@ -0,0 +1,105 @@
+package deviceauth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// SessionKeySize is the AES-256 key size negotiated during the handshake.
+const SessionKeySize = 32
+
+// ParsePublicKeyPEM parses a PEM-encoded PKIX RSA public key, as registered
+// by a device on first boot.
+func ParsePublicKeyPEM(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM public key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not RSA")
+	}
+
+	return rsaPub, nil
+}
+
+// GenerateSessionKey produces a fresh random AES-256 key for a handshake.
+func GenerateSessionKey() ([]byte, error) {
+	key := make([]byte, SessionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate session key: %w", err)
+	}
+	return key, nil
+}
+
+// WrapKeyForDevice encrypts a session key with the device's RSA public key
+// (RSA-OAEP/SHA-256) so only the holder of the matching private key can
+// recover it. This is the server-issued nonce the device decrypts to
+// establish the AES session.
+func WrapKeyForDevice(pub *rsa.PublicKey, key []byte) ([]byte, error) {
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrap session key: %w", err)
+	}
+	return wrapped, nil
+}
+
+// Open decrypts an AES-256-GCM sealed payload (nonce prepended to the
+// ciphertext, as produced by Seal) using the negotiated session key.
+func Open(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("new gcm: %w", err)
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sealed payload too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt payload: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Seal encrypts plaintext with AES-256-GCM under key, prepending the nonce.
+// Provided for tests and for the reference device firmware; the server only
+// ever needs Open, but keeping the inverse alongside it documents the wire
+// format.
+func Seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("new gcm: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
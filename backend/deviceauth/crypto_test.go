@@ -0,0 +1,120 @@
+package deviceauth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+// generateTestKeyPEM returns a fresh 2048-bit RSA key and its PEM-encoded
+// PKIX public key, as a device would register on first boot.
+func generateTestKeyPEM(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	return priv, pemBytes
+}
+
+// TestHandshakeRoundTrip exercises the full handshake: a device registers
+// its public key, the server wraps a fresh session key against it, and the
+// device recovers the exact same key with its private key.
+func TestHandshakeRoundTrip(t *testing.T) {
+	priv, pubPEM := generateTestKeyPEM(t)
+
+	pub, err := ParsePublicKeyPEM(pubPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyPEM: %v", err)
+	}
+
+	sessionKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatalf("GenerateSessionKey: %v", err)
+	}
+	if len(sessionKey) != SessionKeySize {
+		t.Fatalf("len(GenerateSessionKey()) = %d, want %d", len(sessionKey), SessionKeySize)
+	}
+
+	wrapped, err := WrapKeyForDevice(pub, sessionKey)
+	if err != nil {
+		t.Fatalf("WrapKeyForDevice: %v", err)
+	}
+
+	recovered, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, wrapped, nil)
+	if err != nil {
+		t.Fatalf("device-side RSA-OAEP decrypt: %v", err)
+	}
+
+	if !bytes.Equal(recovered, sessionKey) {
+		t.Error("device recovered a different session key than the server wrapped")
+	}
+}
+
+// TestSealOpenRoundTrip checks that Open recovers exactly what Seal
+// produced, matching the wire format the bomb/device firmware (or a test
+// harness standing in for it) uses to talk to the server.
+func TestSealOpenRoundTrip(t *testing.T) {
+	key, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatalf("GenerateSessionKey: %v", err)
+	}
+
+	plaintext := []byte(`{"device_id":"pi-1","is_too_bright":false}`)
+
+	sealed, err := Seal(key, plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	opened, err := Open(key, sealed)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open(Seal(plaintext)) = %q, want %q", opened, plaintext)
+	}
+}
+
+// TestOpenRejectsWrongKey checks that a payload sealed under one session
+// key can't be opened with another, closing a session-confusion hole.
+func TestOpenRejectsWrongKey(t *testing.T) {
+	key, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatalf("GenerateSessionKey: %v", err)
+	}
+	otherKey, err := GenerateSessionKey()
+	if err != nil {
+		t.Fatalf("GenerateSessionKey: %v", err)
+	}
+
+	sealed, err := Seal(key, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	if _, err := Open(otherKey, sealed); err == nil {
+		t.Error("Open succeeded with the wrong session key, want an error")
+	}
+}
+
+// TestParsePublicKeyPEMRejectsGarbage checks that malformed input is
+// rejected rather than silently parsed into a zero-value key.
+func TestParsePublicKeyPEMRejectsGarbage(t *testing.T) {
+	if _, err := ParsePublicKeyPEM([]byte("not a pem block")); err == nil {
+		t.Error("ParsePublicKeyPEM accepted non-PEM input, want an error")
+	}
+}
@@ -0,0 +1,101 @@
+// Package deviceauth implements the RSA+AES handshake that lets photon
+// devices prove their identity on every write instead of trusting a bare
+// device_id in the request body.
+package deviceauth
+
+import (
+	"sync"
+	"time"
+)
+
+// Session is the AES-256 key negotiated with one device during the
+// handshake, plus a heartbeat deadline after which it must be re-negotiated.
+type Session struct {
+	DeviceID  string
+	Key       []byte
+	ExpiresAt time.Time
+}
+
+// SessionManager tracks live device sessions in memory. Sessions are keyed
+// by device_id and expire if the device doesn't touch them within ttl, so a
+// stolen session can't be replayed indefinitely after a device goes dark.
+type SessionManager struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+	ttl      time.Duration
+}
+
+// NewSessionManager creates a manager with the given heartbeat TTL and
+// starts its background reaper.
+func NewSessionManager(ttl time.Duration) *SessionManager {
+	m := &SessionManager{
+		sessions: make(map[string]*Session),
+		ttl:      ttl,
+	}
+	go m.runReaper()
+	return m
+}
+
+// Create installs a fresh session for deviceID, replacing any existing one.
+func (m *SessionManager) Create(deviceID string, key []byte) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session := &Session{
+		DeviceID:  deviceID,
+		Key:       key,
+		ExpiresAt: time.Now().Add(m.ttl),
+	}
+	m.sessions[deviceID] = session
+	return session
+}
+
+// Get returns the live session for deviceID, if any. Expired sessions are
+// treated as absent even before the reaper sweeps them.
+func (m *SessionManager) Get(deviceID string) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.sessions[deviceID]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return nil, false
+	}
+	return session, true
+}
+
+// Heartbeat extends a live session's TTL, called after every successfully
+// authenticated request so well-behaved devices don't get kicked off mid-use.
+func (m *SessionManager) Heartbeat(deviceID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[deviceID]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return false
+	}
+	session.ExpiresAt = time.Now().Add(m.ttl)
+	return true
+}
+
+// Revoke drops deviceID's session, forcing it to re-handshake.
+func (m *SessionManager) Revoke(deviceID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, deviceID)
+}
+
+func (m *SessionManager) runReaper() {
+	ticker := time.NewTicker(m.ttl)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		m.mu.Lock()
+		for deviceID, session := range m.sessions {
+			if now.After(session.ExpiresAt) {
+				delete(m.sessions, deviceID)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,138 @@
+package entropy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// drbgOutBytes is outlen for HMAC-DRBG(SHA-256): the size of both the
+	// internal V value and each Generate output block.
+	drbgOutBytes = sha256.Size
+
+	// reseedInterval is the SP 800-90A mandated reseed interval: 2^48
+	// Generate calls before a DRBG must be reseeded.
+	reseedInterval = 1 << 48
+
+	// maxGenerateBits is the configured cap on bits returned by a single
+	// Generate call (2^19 bits, per SP 800-90A's max-bits-per-request).
+	maxGenerateBits = 1 << 19
+)
+
+// ErrReseedRequired is returned by Generate once reseedInterval calls have
+// been made without an intervening Reseed.
+var ErrReseedRequired = errors.New("entropy: drbg reseed required")
+
+// DRBG implements NIST SP 800-90A's HMAC_DRBG (SHA-256): a deterministic
+// random bit generator seeded once from a true entropy source and then run
+// forward algorithmically, so callers get forward-secure, reproducible
+// per-session output without hitting the shared Pool on every request.
+type DRBG struct {
+	mu sync.Mutex
+
+	key           []byte
+	v             []byte
+	reseedCounter int64
+}
+
+// Instantiate seeds a new DRBG from entropySource (ideally pulled straight
+// from a Pool) and an optional personalization string, per SP 800-90A
+// 10.1.2.3.
+func Instantiate(entropySource, personalizationString []byte) *DRBG {
+	d := &DRBG{
+		key: make([]byte, drbgOutBytes),
+		v:   make([]byte, drbgOutBytes),
+	}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+
+	seedMaterial := make([]byte, 0, len(entropySource)+len(personalizationString))
+	seedMaterial = append(seedMaterial, entropySource...)
+	seedMaterial = append(seedMaterial, personalizationString...)
+	d.update(seedMaterial)
+	d.reseedCounter = 1
+
+	return d
+}
+
+// Reseed mixes fresh entropy (and optional additional input) into the
+// generator and resets the reseed counter, per SP 800-90A 10.1.2.4.
+func (d *DRBG) Reseed(entropySource, additionalInput []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seedMaterial := make([]byte, 0, len(entropySource)+len(additionalInput))
+	seedMaterial = append(seedMaterial, entropySource...)
+	seedMaterial = append(seedMaterial, additionalInput...)
+	d.update(seedMaterial)
+	d.reseedCounter = 1
+}
+
+// Generate returns numBits worth of output (rounded up to the nearest
+// byte), optionally mixing in additionalInput first. It fails once
+// reseedInterval calls have passed since the last reseed, or if numBits
+// exceeds maxGenerateBits.
+func (d *DRBG) Generate(numBits int, additionalInput []byte) ([]byte, error) {
+	if numBits <= 0 || numBits > maxGenerateBits {
+		return nil, fmt.Errorf("entropy: numBits must be in (0, %d]", maxGenerateBits)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.reseedCounter > reseedInterval {
+		return nil, ErrReseedRequired
+	}
+
+	if len(additionalInput) > 0 {
+		d.update(additionalInput)
+	}
+
+	numBytes := (numBits + 7) / 8
+	out := make([]byte, 0, numBytes+drbgOutBytes)
+	for len(out) < numBytes {
+		mac := hmac.New(sha256.New, d.key)
+		mac.Write(d.v)
+		d.v = mac.Sum(nil)
+		out = append(out, d.v...)
+	}
+	out = out[:numBytes]
+
+	d.update(additionalInput)
+	d.reseedCounter++
+
+	return out, nil
+}
+
+// update is the HMAC-DRBG Key/V update recurrence (SP 800-90A 10.1.2.2):
+// Key = HMAC(Key, V || 0x00 || provided), V = HMAC(Key, V), and - only when
+// provided is non-empty - a second round with 0x01 in place of 0x00.
+func (d *DRBG) update(provided []byte) {
+	mac := hmac.New(sha256.New, d.key)
+	mac.Write(d.v)
+	mac.Write([]byte{0x00})
+	mac.Write(provided)
+	d.key = mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, d.key)
+	mac.Write(d.v)
+	d.v = mac.Sum(nil)
+
+	if len(provided) == 0 {
+		return
+	}
+
+	mac = hmac.New(sha256.New, d.key)
+	mac.Write(d.v)
+	mac.Write([]byte{0x01})
+	mac.Write(provided)
+	d.key = mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, d.key)
+	mac.Write(d.v)
+	d.v = mac.Sum(nil)
+}
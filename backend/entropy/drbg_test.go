@@ -0,0 +1,82 @@
+package entropy
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestGenerateDeterministic checks HMAC-DRBG's core property: two DRBGs
+// instantiated from the same entropy and personalization string produce
+// byte-identical output given the same sequence of calls.
+func TestGenerateDeterministic(t *testing.T) {
+	seed := []byte("test entropy source, 32+ bytes long so it looks realistic")
+	perso := []byte("photon-entropy test")
+
+	a := Instantiate(seed, perso)
+	b := Instantiate(seed, perso)
+
+	outA, err := a.Generate(256, nil)
+	if err != nil {
+		t.Fatalf("a.Generate: %v", err)
+	}
+	outB, err := b.Generate(256, nil)
+	if err != nil {
+		t.Fatalf("b.Generate: %v", err)
+	}
+
+	if !bytes.Equal(outA, outB) {
+		t.Errorf("two DRBGs instantiated from the same seed produced different output")
+	}
+}
+
+// TestGenerateAdvancesState checks that consecutive Generate calls on the
+// same DRBG never repeat output, and that output length rounds numBits up
+// to the nearest byte as documented.
+func TestGenerateAdvancesState(t *testing.T) {
+	d := Instantiate([]byte("some entropy"), nil)
+
+	first, err := d.Generate(20, nil)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(first) != 3 {
+		t.Errorf("len(Generate(20, nil)) = %d, want 3 (ceil(20/8))", len(first))
+	}
+
+	second, err := d.Generate(20, nil)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if bytes.Equal(first, second) {
+		t.Errorf("consecutive Generate calls returned identical output; internal state isn't advancing")
+	}
+}
+
+// TestGenerateRejectsInvalidNumBits checks the documented (0, maxGenerateBits]
+// bound on a single Generate call.
+func TestGenerateRejectsInvalidNumBits(t *testing.T) {
+	d := Instantiate([]byte("some entropy"), nil)
+
+	for _, numBits := range []int{0, -1, maxGenerateBits + 1} {
+		if _, err := d.Generate(numBits, nil); err == nil {
+			t.Errorf("Generate(%d, nil) succeeded, want an error", numBits)
+		}
+	}
+}
+
+// TestGenerateRequiresReseedAfterInterval checks that Generate refuses to
+// run once SP 800-90A's reseedInterval has been exceeded without an
+// intervening Reseed, and that Reseed clears the condition.
+func TestGenerateRequiresReseedAfterInterval(t *testing.T) {
+	d := Instantiate([]byte("some entropy"), nil)
+	d.reseedCounter = reseedInterval + 1
+
+	if _, err := d.Generate(128, nil); err != ErrReseedRequired {
+		t.Errorf("Generate after exceeding reseedInterval returned %v, want ErrReseedRequired", err)
+	}
+
+	d.Reseed([]byte("fresh entropy"), nil)
+	if _, err := d.Generate(128, nil); err != nil {
+		t.Errorf("Generate after Reseed returned %v, want nil", err)
+	}
+}
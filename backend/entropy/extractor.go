@@ -0,0 +1,154 @@
+package entropy
+
+import (
+	"crypto/rand"
+	"math"
+	"os"
+	"sync"
+)
+
+const (
+	// extractorSecurityBits is -log2(eps) for the Toeplitz extractor's
+	// statistical distance bound eps=2^-40; the extractable output length
+	// loses 2*extractorSecurityBits to this security margin.
+	extractorSecurityBits = 40
+
+	// initialSeedBytes is how much Toeplitz seed material is generated on
+	// first use; it grows on disk as larger batches demand more.
+	initialSeedBytes = 4096
+)
+
+// Extractor turns a raw, possibly biased sample batch into a shorter,
+// debiased byte stream before it reaches the pool. Implementations are
+// swappable so alternative extraction strategies (e.g. a hashed-DRBG-based
+// extractor) can replace the default without touching callers.
+type Extractor interface {
+	// Extract debiases and compresses samples, using minEntropy (bits per
+	// debiased bit) to size the output. It returns the extracted bytes and
+	// the number of debiased bits the batch yielded before compression.
+	Extract(samples []int, minEntropy float64) (output []byte, debiasedBits int)
+}
+
+// ToeplitzExtractor implements the standard Von Neumann debiasing +
+// Toeplitz-hash randomness extraction pipeline: a bit-level Von Neumann
+// debiaser removes first-order bias from each sample's LSB, then a
+// Toeplitz matrix seeded from a long-lived on-disk key compresses the
+// debiased stream down to (almost) full entropy.
+type ToeplitzExtractor struct {
+	mu sync.Mutex
+
+	seed             []byte
+	compressionRatio float64
+	seedPath         string
+}
+
+// NewExtractor creates a ToeplitzExtractor backed by the seed file at
+// seedPath (created on first use if it doesn't exist). compressionRatio is
+// an extra safety margin in (0, 1] applied on top of the caller-supplied
+// min-entropy estimate before sizing the Toeplitz output; 1 trusts the
+// estimate outright, while a value below 1 extracts more conservatively.
+func NewExtractor(seedPath string, compressionRatio float64) *ToeplitzExtractor {
+	if compressionRatio <= 0 || compressionRatio > 1 {
+		compressionRatio = 1
+	}
+
+	e := &ToeplitzExtractor{seedPath: seedPath, compressionRatio: compressionRatio}
+	e.loadOrGenerateSeed()
+	return e
+}
+
+// Extract implements Extractor.
+func (e *ToeplitzExtractor) Extract(samples []int, minEntropy float64) ([]byte, int) {
+	debiased := vonNeumannDebias(samples)
+	n := len(debiased)
+	if n == 0 {
+		return nil, 0
+	}
+
+	h := minEntropy * e.compressionRatio
+	if h <= 0 {
+		return nil, n
+	}
+
+	outputBits := int(math.Floor(float64(n)*h)) - 2*extractorSecurityBits
+	if outputBits <= 0 {
+		return nil, n
+	}
+
+	e.mu.Lock()
+	e.ensureSeedBitsLocked(n + outputBits - 1)
+	seed := e.seed
+	e.mu.Unlock()
+
+	out := make([]byte, (outputBits+7)/8)
+	for i := 0; i < outputBits; i++ {
+		bit := byte(0)
+		for j := 0; j < n; j++ {
+			if debiased[j] == 1 && seedBit(seed, i+j) == 1 {
+				bit ^= 1
+			}
+		}
+		if bit == 1 {
+			out[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+
+	return out, n
+}
+
+// vonNeumannDebias consumes each sample's LSB in pairs, emitting 0 for "01",
+// 1 for "10", and dropping "00"/"11" pairs outright.
+func vonNeumannDebias(samples []int) []int {
+	bits := make([]int, 0, len(samples)/2)
+	for i := 0; i+1 < len(samples); i += 2 {
+		b0 := samples[i] & 1
+		b1 := samples[i+1] & 1
+		switch {
+		case b0 == 0 && b1 == 1:
+			bits = append(bits, 0)
+		case b0 == 1 && b1 == 0:
+			bits = append(bits, 1)
+		}
+	}
+	return bits
+}
+
+func seedBit(seed []byte, i int) int {
+	return int((seed[i/8] >> uint(7-i%8)) & 1)
+}
+
+func (e *ToeplitzExtractor) loadOrGenerateSeed() {
+	if e.seedPath != "" {
+		if data, err := os.ReadFile(e.seedPath); err == nil && len(data) > 0 {
+			e.seed = data
+			return
+		}
+	}
+
+	e.seed = make([]byte, initialSeedBytes)
+	_, _ = rand.Read(e.seed)
+	e.persistSeedLocked()
+}
+
+// ensureSeedBitsLocked grows the seed, persisting the extension, whenever a
+// batch needs more Toeplitz seed material than is currently stored.
+func (e *ToeplitzExtractor) ensureSeedBitsLocked(bitsNeeded int) {
+	bytesNeeded := (bitsNeeded + 7) / 8
+	if len(e.seed) >= bytesNeeded {
+		return
+	}
+
+	extra := make([]byte, bytesNeeded-len(e.seed))
+	_, _ = rand.Read(extra)
+	e.seed = append(e.seed, extra...)
+	e.persistSeedLocked()
+}
+
+// persistSeedLocked is best-effort: losing the seed file only means a fresh
+// one is generated on next start, not a correctness issue.
+func (e *ToeplitzExtractor) persistSeedLocked() {
+	if e.seedPath == "" {
+		return
+	}
+	_ = os.WriteFile(e.seedPath, e.seed, 0600)
+}
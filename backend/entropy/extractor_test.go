@@ -0,0 +1,87 @@
+package entropy
+
+import (
+	"math"
+	"testing"
+)
+
+// TestVonNeumannDebiasPairs checks the documented Von Neumann mapping: "01"
+// emits 0, "10" emits 1, and "00"/"11" pairs are dropped outright.
+func TestVonNeumannDebiasPairs(t *testing.T) {
+	// Samples are compared by their LSB only; use values whose LSB spells
+	// out the pairs 00, 01, 10, 11, followed by a lone trailing 0 (dropped,
+	// since it has no partner).
+	samples := []int{0, 0, 0, 1, 1, 0, 1, 1, 0}
+	got := vonNeumannDebias(samples)
+	want := []int{0, 1}
+
+	if len(got) != len(want) {
+		t.Fatalf("vonNeumannDebias(%v) = %v, want %v", samples, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("vonNeumannDebias(%v)[%d] = %d, want %d", samples, i, got[i], want[i])
+		}
+	}
+}
+
+// TestExtractOutputLength checks the documented output-length formula:
+// outputBits = floor(n*h) - 2*extractorSecurityBits, where n is the
+// debiased bit count and h is minEntropy*compressionRatio.
+func TestExtractOutputLength(t *testing.T) {
+	e := &ToeplitzExtractor{compressionRatio: 1}
+	e.loadOrGenerateSeed()
+
+	// 400 alternating-LSB samples debias to n=200 bits (no drops).
+	samples := make([]int, 400)
+	for i := range samples {
+		samples[i] = i % 2
+	}
+
+	minEntropy := 1.0
+	_, debiasedBits := e.Extract(samples, minEntropy)
+	if debiasedBits != 200 {
+		t.Fatalf("debiasedBits = %d, want 200", debiasedBits)
+	}
+
+	wantOutputBits := int(math.Floor(float64(debiasedBits)*minEntropy)) - 2*extractorSecurityBits
+	wantOutputBytes := (wantOutputBits + 7) / 8
+
+	out, _ := e.Extract(samples, minEntropy)
+	if len(out) != wantOutputBytes {
+		t.Errorf("len(Extract output) = %d, want %d", len(out), wantOutputBytes)
+	}
+}
+
+// TestExtractReturnsNilWhenEntropyInsufficient checks that a minEntropy too
+// low to clear the security margin yields no output, just the debiased bit
+// count for the caller's accounting - not a panic or negative-length slice.
+func TestExtractReturnsNilWhenEntropyInsufficient(t *testing.T) {
+	e := &ToeplitzExtractor{compressionRatio: 1}
+	e.loadOrGenerateSeed()
+
+	samples := make([]int, 20)
+	for i := range samples {
+		samples[i] = i % 2
+	}
+
+	out, debiasedBits := e.Extract(samples, 0.01)
+	if out != nil {
+		t.Errorf("Extract with insufficient entropy returned %d bytes, want nil", len(out))
+	}
+	if debiasedBits != 10 {
+		t.Errorf("debiasedBits = %d, want 10", debiasedBits)
+	}
+}
+
+// TestExtractEmptyInput checks that an input too short to debias at all
+// (fewer than 2 samples) returns nil/0 rather than indexing out of range.
+func TestExtractEmptyInput(t *testing.T) {
+	e := &ToeplitzExtractor{compressionRatio: 1}
+	e.loadOrGenerateSeed()
+
+	out, debiasedBits := e.Extract([]int{1}, 1.0)
+	if out != nil || debiasedBits != 0 {
+		t.Errorf("Extract([]int{1}, 1.0) = (%v, %d), want (nil, 0)", out, debiasedBits)
+	}
+}
@@ -1,90 +1,255 @@
 package entropy
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/sha256"
 	"encoding/binary"
+	"hash"
+	"os"
 	"sync"
+	"time"
 )
 
+const (
+	// numPools is the number of independent SHA-256 accumulators samples are
+	// spread across. Pool 0 is checked on every reseed attempt and feeds
+	// every reseed; pool i only feeds reseed number R when 2^i divides R, so
+	// higher-index pools accumulate far more entropy before they're drawn
+	// down, exactly as in Fortuna's original pool-rotation schedule.
+	numPools = 32
+
+	// minReseedBytes and minReseedInterval gate how eagerly pool 0 can
+	// trigger a reseed, so a burst of low-entropy submissions can't spin the
+	// generator's key through reseeds faster than real entropy arrives.
+	minReseedBytes    = 64
+	minReseedInterval = 100 * time.Millisecond
+
+	// rekeyAfterBytes bounds how much keystream is drawn from a single
+	// AES-256-CTR instantiation before the generator rekeys itself from its
+	// own output, limiting how much any one key is ever exposed through.
+	rekeyAfterBytes = 1 << 20 // 1 MiB
+)
+
+// Pool is a Fortuna-style entropy accumulator. Incoming samples are
+// distributed round-robin across numPools running SHA-256 contexts, so a
+// single contaminated or low-quality submission can only ever taint the one
+// pool it landed in rather than the entire accumulator. Output comes from an
+// AES-256-CTR generator keyed from those pools, reseeded opportunistically
+// and rekeyed periodically from its own stream for forward secrecy.
 type Pool struct {
-	mu       sync.RWMutex
-	data     []byte
-	maxSize  int
-	position int
+	mu sync.Mutex
+
+	pools     [numPools]hash.Hash
+	poolBytes [numPools]int
+	nextPool  int
+
+	key         [32]byte
+	reseedCount uint64
+	lastReseed  time.Time
+
+	stream      cipher.Stream
+	streamBytes int
+	totalAdded  int
+	maxSize     int
+
+	seedPath string
 }
 
-func NewPool(maxSize int) *Pool {
-	return &Pool{
-		data:    make([]byte, 0, maxSize),
-		maxSize: maxSize,
+// NewPool creates a Fortuna-style accumulator with the given reporting
+// capacity (used only for Size/MaxSize; the pools themselves are unbounded).
+// If seedPath is non-empty and a sealed seed file already exists there, its
+// contents reseed the generator immediately so entropy collected before a
+// restart isn't lost; the file is then overwritten so it is never reused.
+func NewPool(maxSize int, seedPath string) *Pool {
+	p := &Pool{maxSize: maxSize, seedPath: seedPath}
+	for i := range p.pools {
+		p.pools[i] = sha256.New()
 	}
+	p.loadSeed()
+	return p
 }
 
+// Add distributes raw samples round-robin across the accumulator pools.
 func (p *Pool) Add(samples []int) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	for _, sample := range samples {
-		lsb := byte(sample & 0xFF)
-		if len(p.data) < p.maxSize {
-			p.data = append(p.data, lsb)
-		} else {
-			p.data[p.position] = lsb
-			p.position = (p.position + 1) % p.maxSize
-		}
+		p.pools[p.nextPool].Write([]byte{byte(sample & 0xFF)})
+		p.poolBytes[p.nextPool]++
+		p.nextPool = (p.nextPool + 1) % numPools
+		p.totalAdded++
 	}
 }
 
+// Size reports the accumulated sample count, capped at MaxSize for display
+// purposes (the underlying pools never actually stop accepting samples).
 func (p *Pool) Size() int {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	return len(p.data)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.totalAdded > p.maxSize {
+		return p.maxSize
+	}
+	return p.totalAdded
+}
+
+// MaxSize returns the pool's configured reporting capacity.
+func (p *Pool) MaxSize() int {
+	return p.maxSize
 }
 
+// GetBytes returns n bytes from the generator, reseeding from the
+// accumulator pools first if enough has built up since the last reseed. It
+// returns nil if the generator has never been seeded.
 func (p *Pool) GetBytes(n int) []byte {
+	if n <= 0 {
+		return nil
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if len(p.data) == 0 {
+	p.maybeReseedLocked()
+	if p.reseedCount == 0 {
 		return nil
 	}
 
-	result := make([]byte, 0, n)
-	for len(result) < n {
-		hash := sha256.Sum256(p.data)
-		p.mixPool(hash[:])
-		result = append(result, hash[:min(n-len(result), 32)]...)
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		if p.stream == nil {
+			p.rekeyLocked()
+		}
+
+		chunk := n - len(out)
+		if remaining := rekeyAfterBytes - p.streamBytes; chunk > remaining {
+			chunk = remaining
+		}
+		if chunk <= 0 {
+			p.rekeyLocked()
+			continue
+		}
+
+		buf := make([]byte, chunk)
+		p.stream.XORKeyStream(buf, buf)
+		out = append(out, buf...)
+		p.streamBytes += chunk
 	}
 
-	return result[:n]
+	return out
 }
 
+// GetInt returns a uniform random value in [min, max) using rejection
+// sampling, so the result carries no modulo bias regardless of rangeSize.
 func (p *Pool) GetInt(min, max int64) (int64, bool) {
 	if min >= max {
 		return 0, false
 	}
 
-	bytes := p.GetBytes(8)
-	if bytes == nil {
-		return 0, false
+	rangeSize := uint64(max - min)
+	limit := (^uint64(0) / rangeSize) * rangeSize
+
+	for {
+		bytes := p.GetBytes(8)
+		if bytes == nil {
+			return 0, false
+		}
+
+		raw := binary.BigEndian.Uint64(bytes)
+		if raw < limit {
+			return min + int64(raw%rangeSize), true
+		}
 	}
+}
 
-	raw := binary.BigEndian.Uint64(bytes)
-	rangeSize := uint64(max - min)
-	value := min + int64(raw%rangeSize)
+// maybeReseedLocked implements Fortuna's reseed schedule: once pool 0 holds
+// at least minReseedBytes and minReseedInterval has passed since the last
+// reseed, the reseed counter R is incremented and every pool i for which 2^i
+// divides R is folded into the generator key and reset.
+func (p *Pool) maybeReseedLocked() {
+	if p.poolBytes[0] < minReseedBytes {
+		return
+	}
+	if !p.lastReseed.IsZero() && time.Since(p.lastReseed) < minReseedInterval {
+		return
+	}
 
-	return value, true
+	p.reseedCount++
+	key := p.key
+	for i := 0; i < numPools; i++ {
+		if p.reseedCount%(uint64(1)<<uint(i)) != 0 {
+			break
+		}
+
+		digest := p.pools[i].Sum(nil)
+		mixed := sha256.Sum256(append(append([]byte{}, key[:]...), digest...))
+		key = mixed
+
+		p.pools[i] = sha256.New()
+		p.poolBytes[i] = 0
+	}
+
+	p.key = key
+	p.lastReseed = time.Now()
+	p.stream = nil
+	p.saveSeed()
 }
 
-func (p *Pool) mixPool(hash []byte) {
-	for i := 0; i < len(p.data) && i < len(hash); i++ {
-		p.data[i] ^= hash[i]
+// rekeyLocked starts a fresh AES-256-CTR instance. Once the generator has
+// already produced output this generation, it derives the new key from its
+// own keystream rather than from the pool-derived key directly, so draining
+// rekeyAfterBytes of output never exposes more than one key's worth.
+func (p *Pool) rekeyLocked() {
+	if p.stream != nil {
+		rekeyBuf := make([]byte, 32)
+		p.stream.XORKeyStream(rekeyBuf, rekeyBuf)
+		copy(p.key[:], rekeyBuf)
 	}
+
+	block, err := aes.NewCipher(p.key[:])
+	if err != nil {
+		// p.key is always exactly 32 bytes, so NewCipher cannot fail here.
+		panic(err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[aes.BlockSize-8:], p.reseedCount)
+
+	p.stream = cipher.NewCTR(block, iv)
+	p.streamBytes = 0
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+func (p *Pool) loadSeed() {
+	if p.seedPath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(p.seedPath)
+	if err != nil || len(data) != 32+8 {
+		return
 	}
-	return b
+
+	copy(p.key[:], data[:32])
+	p.reseedCount = binary.BigEndian.Uint64(data[32:])
+	p.lastReseed = time.Now()
+
+	// The seed must never be reused: overwrite it with fresh state derived
+	// from the key we just loaded before anything can read it from disk.
+	p.key = sha256.Sum256(p.key[:])
+	p.rekeyLocked()
+	p.saveSeed()
+}
+
+// saveSeed writes the sealed seed file: just enough state to resume the
+// generator after a restart without replaying raw samples. Best-effort -
+// losing this file only costs one generation's head start, not correctness.
+func (p *Pool) saveSeed() {
+	if p.seedPath == "" {
+		return
+	}
+
+	data := make([]byte, 32+8)
+	copy(data[:32], p.key[:])
+	binary.BigEndian.PutUint64(data[32:], p.reseedCount)
+
+	_ = os.WriteFile(p.seedPath, data, 0600)
 }
@@ -0,0 +1,81 @@
+package entropy
+
+import "testing"
+
+// TestPoolGetBytesNilBeforeSeed checks that GetBytes refuses to produce
+// output before the accumulator has ever reseeded the generator, rather
+// than returning predictable all-zero keystream.
+func TestPoolGetBytesNilBeforeSeed(t *testing.T) {
+	p := NewPool(1024, "")
+
+	if out := p.GetBytes(16); out != nil {
+		t.Errorf("GetBytes before any Add returned %d bytes, want nil", len(out))
+	}
+}
+
+// TestPoolGetBytesAfterReseed checks that once enough samples have landed
+// in pool 0 to trigger Fortuna's reseed condition, GetBytes starts
+// producing output of the requested length.
+func TestPoolGetBytesAfterReseed(t *testing.T) {
+	p := NewPool(1024, "")
+
+	samples := make([]int, minReseedBytes*numPools)
+	for i := range samples {
+		samples[i] = i
+	}
+	p.Add(samples)
+
+	out := p.GetBytes(32)
+	if len(out) != 32 {
+		t.Fatalf("GetBytes(32) returned %d bytes, want 32", len(out))
+	}
+}
+
+// TestPoolGetIntRange checks that GetInt's rejection sampling always stays
+// within [min, max) across many draws, never returning a value outside the
+// requested range or leaning on modulo bias at the boundaries.
+func TestPoolGetIntRange(t *testing.T) {
+	p := NewPool(1024, "")
+	samples := make([]int, minReseedBytes*numPools)
+	for i := range samples {
+		samples[i] = i * 7
+	}
+	p.Add(samples)
+
+	min, max := int64(10), int64(13)
+	for i := 0; i < 200; i++ {
+		v, ok := p.GetInt(min, max)
+		if !ok {
+			t.Fatalf("GetInt(%d, %d) reported !ok after a successful reseed", min, max)
+		}
+		if v < min || v >= max {
+			t.Fatalf("GetInt(%d, %d) = %d, out of range", min, max, v)
+		}
+	}
+}
+
+// TestPoolGetIntRejectsEmptyRange checks the documented min>=max guard.
+func TestPoolGetIntRejectsEmptyRange(t *testing.T) {
+	p := NewPool(1024, "")
+	if _, ok := p.GetInt(5, 5); ok {
+		t.Error("GetInt(5, 5) reported ok, want false for an empty range")
+	}
+	if _, ok := p.GetInt(5, 3); ok {
+		t.Error("GetInt(5, 3) reported ok, want false for an inverted range")
+	}
+}
+
+// TestPoolSizeCapsAtMaxSize checks that Size() reports at most the
+// configured reporting capacity even once more samples have been added.
+func TestPoolSizeCapsAtMaxSize(t *testing.T) {
+	p := NewPool(10, "")
+	samples := make([]int, 25)
+	p.Add(samples)
+
+	if size := p.Size(); size != 10 {
+		t.Errorf("Size() = %d, want 10 (capped at MaxSize)", size)
+	}
+	if p.MaxSize() != 10 {
+		t.Errorf("MaxSize() = %d, want 10", p.MaxSize())
+	}
+}
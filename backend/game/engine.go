@@ -1,36 +1,484 @@
 package game
 
 import (
+	"bytes"
+	"compress/gzip"
 	"crypto/rand"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
+// ErrActionRateLimited is returned by ProcessAction when a game's client is
+// mashing actions faster than its per-game throttle allows, so a runaway
+// expert can't strike-flood a game or DOS the engine.
+var ErrActionRateLimited = errors.New("action rate limit exceeded for this game")
+
+// StaleLobbyTimeout is how long a lobby can sit without both roles connected
+// before the janitor reaps it, so abandoned matchmaking attempts don't pile
+// up in the open-game pool.
+const StaleLobbyTimeout = 10 * time.Minute
+
+// DisconnectGraceTimeout is how long a role has to reconnect to its game's
+// WebSocket after an unexpected disconnect before the game auto-pauses.
+const DisconnectGraceTimeout = 15 * time.Second
+
 // Engine manages all active games
 type Engine struct {
-	games      map[string]*Game // game ID -> game
-	codeIndex  map[string]string // join code -> game ID
-	mu         sync.RWMutex
+	games     map[string]*Game  // game ID -> game
+	codeIndex map[string]string // join code -> game ID
+	mu        sync.RWMutex
+
+	// store persists a snapshot of every game on each state transition, so
+	// a restart can rehydrate in-flight games via Restore instead of losing
+	// them. Defaults to a MemoryStore, which offers no actual persistence;
+	// call SetStore with a BoltStore/SQLiteStore before Restore for that.
+	store Store
+
+	// actionLimiters throttles ProcessAction per game_id so a single client
+	// can't flood the engine with actions. Entries are evicted once the
+	// game ends so the map doesn't grow unbounded across game churn.
+	actionLimiters   map[string]*rate.Limiter
+	actionLimitersMu sync.Mutex
+	actionRPS        float64
+	actionBurst      int
+
+	// actionLog holds every ProcessAction outcome per game, in order, so
+	// reconnecting WebSocket clients can replay what they missed and
+	// operators can pull a full defusal timeline after the fact.
+	actionLog   map[string][]ActionLogEntry
+	actionSeq   map[string]int64
+	actionLogMu sync.RWMutex
+
+	// replayLog holds every GameEvent emitted for a game, in order, plus the
+	// wall-clock offset from the game's start, so ExportReplay can hand a
+	// bug report or "watch again" tool a full, replayable timeline.
+	// replayStartedAt records when each game actually started, independent
+	// of Game.mu, since emitEvent can run with that lock already held.
+	replayLog       map[string][]ReplayEntry
+	replayStartedAt map[string]time.Time
+	replayLogMu     sync.Mutex
+
+	// replayDir, if set via SetReplayDir, is where persistReplay writes a
+	// gzip-compressed NDJSON export of a game's replay log once it ends, so
+	// the log survives CleanupGame's in-memory eviction. Empty disables
+	// on-disk export entirely.
+	replayDir string
+
+	// disconnectTimers holds the pending auto-pause timer for each game/role
+	// that disconnected from its WebSocket, keyed by "<gameID>:<role>". A
+	// timer is cancelled if the role reconnects within DisconnectGraceTimeout.
+	disconnectTimers   map[string]*time.Timer
+	disconnectTimersMu sync.Mutex
+
+	// profiles tracks each client token's per-ModuleType success/failure
+	// rate across games, so createGameLocked can bias module generation
+	// toward a player's weakest categories.
+	profiles *ProfileStore
+
+	// history persists normalized match history (games/players/modules/
+	// events) for the /game/history and /game/:id endpoints. Defaults to
+	// NoopHistoryStore; call SetHistoryStore with a SQLHistoryStore to make
+	// match history survive past a game's in-memory lifetime.
+	history HistoryStore
 
 	// Callbacks for events (to be set by handler)
 	OnGameEvent func(event GameEvent)
+
+	// OnAction, if set, is invoked synchronously after each action is
+	// appended to the in-memory log, so the handler layer can persist it
+	// (e.g. to the game_actions table) for post-restart replay.
+	OnAction func(entry ActionLogEntry)
+}
+
+// ActionLogEntry is one sequentially numbered ProcessAction outcome. The
+// sequence number is scoped to its game and is the resume token reconnecting
+// WebSocket clients send back as last_seq.
+type ActionLogEntry struct {
+	Seq       int64         `json:"seq"`
+	GameID    string        `json:"game_id"`
+	ModuleID  string        `json:"module_id"`
+	Action    string        `json:"action"`
+	Value     interface{}   `json:"value"`
+	Result    *ActionResult `json:"result"`
+	Timestamp time.Time     `json:"timestamp"`
 }
 
-// NewEngine creates a new game engine
-func NewEngine() *Engine {
-	return &Engine{
-		games:     make(map[string]*Game),
-		codeIndex: make(map[string]string),
+// NewEngine creates a new game engine. actionRPS/actionBurst configure the
+// per-game ProcessAction throttle; pass the values from config.RateLimitConfig.
+func NewEngine(actionRPS float64, actionBurst int) *Engine {
+	e := &Engine{
+		games:            make(map[string]*Game),
+		codeIndex:        make(map[string]string),
+		store:            NewMemoryStore(),
+		actionLimiters:   make(map[string]*rate.Limiter),
+		actionRPS:        actionRPS,
+		actionBurst:      actionBurst,
+		actionLog:        make(map[string][]ActionLogEntry),
+		actionSeq:        make(map[string]int64),
+		replayLog:        make(map[string][]ReplayEntry),
+		replayStartedAt:  make(map[string]time.Time),
+		disconnectTimers: make(map[string]*time.Timer),
+		profiles:         NewProfileStore(),
+		history:          NoopHistoryStore{},
 	}
+	go e.runLobbyJanitor()
+	return e
 }
 
-// CreateGame creates a new game with the specified settings
-func (e *Engine) CreateGame(timeLimit, modulesCount, maxStrikes int) (*Game, error) {
+// snapshotTickEvery is how often (in timer ticks, i.e. seconds) runTimer
+// snapshots a playing game's state, so a crash loses at most this many
+// seconds of progress rather than the whole game.
+const snapshotTickEvery = 5
+
+// SetStore configures the Store used to persist game state across
+// restarts. Call it once, before Restore and before any games are created;
+// it defaults to a MemoryStore, which doesn't actually survive a restart.
+func (e *Engine) SetStore(s Store) {
+	e.store = s
+}
+
+// SetHistoryStore configures where match history (games/players/modules/
+// events) is persisted. Call it before serving traffic; Engine otherwise
+// keeps using the default NoopHistoryStore, which discards every record.
+func (e *Engine) SetHistoryStore(h HistoryStore) {
+	e.history = h
+}
+
+// SetReplayDir configures the directory persistReplay writes gzip-compressed
+// NDJSON replay exports to when a game ends. Call it before serving traffic;
+// Engine otherwise leaves replayDir empty, which disables on-disk export
+// (ExportReplay/GetReplay still work for games still held in memory).
+func (e *Engine) SetReplayDir(dir string) {
+	e.replayDir = dir
+}
+
+// Restore rehydrates every active game from the configured Store and
+// resumes its timer if it was still playing, so a process restart doesn't
+// lose in-flight defusal attempts.
+func (e *Engine) Restore() error {
+	snapshots, err := e.store.ListActive()
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	for _, gs := range snapshots {
+		g := restoreGame(gs)
+		e.games[g.ID] = g
+		e.codeIndex[g.Code] = g.ID
+	}
+	e.mu.Unlock()
+
+	for _, gs := range snapshots {
+		if gs.State == StatePlaying {
+			go e.runTimer(gs.ID)
+		}
+	}
+
+	return nil
+}
+
+// saveSnapshot persists gs, best-effort: a failed save only risks losing
+// progress on the next crash, not the correctness of the running game.
+func (e *Engine) saveSnapshot(gs GameSnapshot) {
+	_ = e.store.SaveGame(gs)
+}
+
+// recordHistory upserts gs's summary row into the configured HistoryStore,
+// best-effort like saveSnapshot: a failed write only risks an incomplete
+// match-history record, not the correctness of the running game.
+func (e *Engine) recordHistory(gs GameSnapshot) {
+	_ = e.history.RecordGame(GameHistoryRecord{
+		ID:           gs.ID,
+		Code:         gs.Code,
+		Seed:         gs.Seed,
+		TimeLimit:    gs.TimeLimit,
+		MaxStrikes:   gs.MaxStrikes,
+		PlayMode:     gs.PlayMode,
+		StageSize:    gs.StageSize,
+		Difficulty:   gs.Difficulty,
+		PlayerToken:  gs.PlayerToken,
+		State:        gs.State,
+		Strikes:      gs.Strikes,
+		TimeLeft:     gs.TimeLeft,
+		ModulesCount: gs.ModulesCount,
+		CreatedAt:    gs.CreatedAt,
+		StartedAt:    gs.StartedAt,
+		EndedAt:      gs.EndedAt,
+	})
+}
+
+// recordHistoryEvent best-effort appends one lifecycle event to gameID's
+// history timeline.
+func (e *Engine) recordHistoryEvent(gameID, eventType, moduleID, message string) {
+	_ = e.history.RecordEvent(GameHistoryEvent{
+		GameID:    gameID,
+		Type:      eventType,
+		ModuleID:  moduleID,
+		Message:   message,
+		CreatedAt: time.Now(),
+	})
+}
+
+// GetGameHistory returns the full persisted history (summary, players,
+// modules, event timeline) for a single match, including ones no longer
+// held in memory.
+func (e *Engine) GetGameHistory(gameID string) (GameHistory, error) {
+	return e.history.GetGameHistory(gameID)
+}
+
+// ListGameHistory returns the most recent persisted matches, newest first.
+func (e *Engine) ListGameHistory(limit, offset int) ([]GameHistoryRecord, error) {
+	return e.history.ListGameHistory(limit, offset)
+}
+
+// ReplayEntry is one recorded GameEvent, annotated with its wall-clock
+// offset from the game's StartedAt so a Replayer can reproduce timing.
+// Offset is 0 for events emitted before the game starts.
+type ReplayEntry struct {
+	GameEvent
+	OffsetSeconds float64 `json:"offset_seconds"`
+}
+
+// appendReplayLog records event under its game's replay log. It only ever
+// takes replayLogMu, never e.mu or a Game's mu, since emitEvent can run with
+// either already held by its caller.
+func (e *Engine) appendReplayLog(event GameEvent) {
+	e.replayLogMu.Lock()
+	defer e.replayLogMu.Unlock()
+
+	offset := 0.0
+	if started, ok := e.replayStartedAt[event.GameID]; ok {
+		offset = event.Timestamp.Sub(started).Seconds()
+	}
+
+	e.replayLog[event.GameID] = append(e.replayLog[event.GameID], ReplayEntry{
+		GameEvent:     event,
+		OffsetSeconds: offset,
+	})
+}
+
+// ExportReplay serializes gameID's full replay log as newline-delimited
+// JSON (one ReplayEntry per line), suitable for a bug report attachment,
+// tournament result verification, or feeding back into a Replayer.
+func (e *Engine) ExportReplay(gameID string) ([]byte, error) {
+	e.mu.RLock()
+	_, ok := e.games[gameID]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("game not found")
+	}
+
+	e.replayLogMu.Lock()
+	entries := append([]ReplayEntry(nil), e.replayLog[gameID]...)
+	e.replayLogMu.Unlock()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// replayPath is where persistReplay writes (and LoadReplay reads) gameID's
+// on-disk export under e.replayDir.
+func (e *Engine) replayPath(gameID string) string {
+	return filepath.Join(e.replayDir, gameID+".ndjson.gz")
+}
+
+// persistReplay gzip-compresses gameID's replay log and writes it to
+// e.replayDir, same best-effort semantics as saveSnapshot: failures (no
+// replayDir configured, a full disk) are silently dropped rather than
+// surfaced to the caller, since losing a replay export shouldn't affect the
+// game that produced it.
+func (e *Engine) persistReplay(gameID string) {
+	if e.replayDir == "" {
+		return
+	}
+
+	data, err := e.ExportReplay(gameID)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(e.replayDir, 0755); err != nil {
+		return
+	}
+
+	f, err := os.Create(e.replayPath(gameID))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	defer gw.Close()
+	_, _ = gw.Write(data)
+}
+
+// LoadReplay reads gameID's persisted replay export back off disk,
+// decompressing it, for games no longer held in memory (ExportReplay only
+// covers games still in e.games). Returns an error if no replayDir is
+// configured or no export exists for gameID.
+func (e *Engine) LoadReplay(gameID string) ([]byte, error) {
+	if e.replayDir == "" {
+		return nil, fmt.Errorf("no replay directory configured")
+	}
+
+	f, err := os.Open(e.replayPath(gameID))
+	if err != nil {
+		return nil, fmt.Errorf("replay not found: %w", err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	return io.ReadAll(gr)
+}
+
+// ReconstructGame replays gameID's own recorded event log through a fresh
+// Replayer and returns the result, so callers can diff it against the live
+// game to catch a nondeterminism bug (a processModuleAction or RuleGenerator
+// change that broke replay without anyone noticing) before it corrupts a
+// tournament result. Only covers games still held in memory, same as
+// ExportReplay.
+func (e *Engine) ReconstructGame(gameID string) (*Game, error) {
+	e.mu.RLock()
+	g, ok := e.games[gameID]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("game not found")
+	}
+
+	g.mu.RLock()
+	seed := g.Seed
+	timeLimit := g.TimeLimit
+	modulesCount := g.ModulesCount
+	maxStrikes := g.MaxStrikes
+	playMode := g.PlayMode
+	stageSize := g.StageSize
+	difficulty := g.Difficulty
+	biasedTypes := append([]ModuleType(nil), g.BiasedTypes...)
+	g.mu.RUnlock()
+
+	e.replayLogMu.Lock()
+	entries := append([]ReplayEntry(nil), e.replayLog[gameID]...)
+	e.replayLogMu.Unlock()
+
+	return NewReplayer().Replay(seed, timeLimit, modulesCount, maxStrikes, playMode, stageSize, difficulty, biasedTypes, entries), nil
+}
+
+// actionLimiterFor returns (creating if necessary) the per-game limiter used
+// to throttle ProcessAction calls.
+func (e *Engine) actionLimiterFor(gameID string) *rate.Limiter {
+	e.actionLimitersMu.Lock()
+	defer e.actionLimitersMu.Unlock()
+
+	limiter, ok := e.actionLimiters[gameID]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(e.actionRPS), e.actionBurst)
+		e.actionLimiters[gameID] = limiter
+	}
+	return limiter
+}
+
+// evictActionLimiter drops gameID's throttle once its game ends, so the map
+// doesn't accumulate one entry per game forever.
+func (e *Engine) evictActionLimiter(gameID string) {
+	e.actionLimitersMu.Lock()
+	defer e.actionLimitersMu.Unlock()
+	delete(e.actionLimiters, gameID)
+}
+
+// appendActionLog assigns the next sequence number for gameID and appends
+// the resulting entry to its in-memory log.
+func (e *Engine) appendActionLog(gameID, moduleID, action string, value interface{}, result *ActionResult) ActionLogEntry {
+	e.actionLogMu.Lock()
+	defer e.actionLogMu.Unlock()
+
+	e.actionSeq[gameID]++
+	entry := ActionLogEntry{
+		Seq:       e.actionSeq[gameID],
+		GameID:    gameID,
+		ModuleID:  moduleID,
+		Action:    action,
+		Value:     value,
+		Result:    result,
+		Timestamp: time.Now(),
+	}
+	e.actionLog[gameID] = append(e.actionLog[gameID], entry)
+	return entry
+}
+
+// GetActionsSince returns gameID's action log entries with Seq > sinceSeq,
+// in order. Used both for the replay endpoint and to catch up reconnecting
+// WebSocket clients.
+func (e *Engine) GetActionsSince(gameID string, sinceSeq int64) ([]ActionLogEntry, error) {
+	e.mu.RLock()
+	_, ok := e.games[gameID]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("game not found")
+	}
+
+	e.actionLogMu.RLock()
+	defer e.actionLogMu.RUnlock()
+
+	log := e.actionLog[gameID]
+	result := make([]ActionLogEntry, 0, len(log))
+	for _, entry := range log {
+		if entry.Seq > sinceSeq {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// GetAction returns a single action log entry by its sequence number.
+func (e *Engine) GetAction(gameID string, seq int64) (ActionLogEntry, error) {
+	e.actionLogMu.RLock()
+	defer e.actionLogMu.RUnlock()
+
+	for _, entry := range e.actionLog[gameID] {
+		if entry.Seq == seq {
+			return entry, nil
+		}
+	}
+	return ActionLogEntry{}, fmt.Errorf("action not found: game=%s seq=%d", gameID, seq)
+}
+
+// CreateGame creates a new game with the specified settings. playerToken, if
+// non-empty, biases module generation toward that player's weakest
+// categories (per GetPlayerStats) and is later credited with this game's
+// ProcessAction outcomes.
+func (e *Engine) CreateGame(timeLimit, modulesCount, maxStrikes int, playMode PlayMode, stageSize int, difficulty Difficulty, playerToken string) (*Game, error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	return e.createGameLocked(timeLimit, modulesCount, maxStrikes, playMode, stageSize, difficulty, playerToken)
+}
+
+// createGameLocked does the actual work of CreateGame; callers must already
+// hold e.mu so matchmaking can create a fallback game atomically with its
+// failed scan of existing lobbies.
+func (e *Engine) createGameLocked(timeLimit, modulesCount, maxStrikes int, playMode PlayMode, stageSize int, difficulty Difficulty, playerToken string) (*Game, error) {
 	// Generate unique ID and join code
 	gameID := e.generateID()
 	joinCode := e.generateJoinCode()
@@ -44,8 +492,15 @@ func (e *Engine) CreateGame(timeLimit, modulesCount, maxStrikes int) (*Game, err
 	// Generate seed for deterministic rules
 	seed := e.generateSeed()
 
+	// Bias generation toward whatever module categories this player has
+	// historically failed most, if they've played before.
+	var biasedTypes []ModuleType
+	if playerToken != "" {
+		biasedTypes = e.profiles.Get(playerToken).WeakestTypes(len(AllModuleTypes))
+	}
+
 	// Generate modules
-	ruleGen := NewRuleGenerator(seed)
+	ruleGen := NewRuleGenerator(seed, difficulty, biasedTypes)
 	modules := ruleGen.GenerateModules(modulesCount)
 
 	game := &Game{
@@ -59,11 +514,23 @@ func (e *Engine) CreateGame(timeLimit, modulesCount, maxStrikes int) (*Game, err
 		MaxStrikes:   maxStrikes,
 		Modules:      modules,
 		ModulesCount: modulesCount,
+		PlayMode:     playMode,
+		StageSize:    stageSize,
+		Difficulty:   difficulty,
+		PlayerToken:  playerToken,
+		BiasedTypes:  biasedTypes,
 		CreatedAt:    time.Now(),
+		moduleLocks:  make([]sync.Mutex, len(modules)),
 	}
 
 	e.games[gameID] = game
 	e.codeIndex[joinCode] = gameID
+	e.saveSnapshot(snapshotOf(game))
+	e.recordHistory(snapshotOf(game))
+	for _, m := range modules {
+		_ = e.history.RecordModule(GameHistoryModule{GameID: gameID, ModuleID: m.ID, Type: m.Type})
+	}
+	e.recordHistoryEvent(gameID, EventGameCreated, "", "")
 
 	// Emit event
 	e.emitEvent(GameEvent{
@@ -74,12 +541,128 @@ func (e *Engine) CreateGame(timeLimit, modulesCount, maxStrikes int) (*Game, err
 			"code":          joinCode,
 			"time_limit":    timeLimit,
 			"modules_count": modulesCount,
+			"difficulty":    difficulty,
 		},
 	})
 
 	return game, nil
 }
 
+// FindOpenGame returns the oldest game still in the lobby that is missing
+// the given role, without joining it. Callers racing each other should
+// prefer Matchmake, which finds and joins atomically.
+func (e *Engine) FindOpenGame(role string) (*Game, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var oldest *Game
+	for _, g := range e.games {
+		if g.State != StateLobby || !roleIsOpen(g, role) {
+			continue
+		}
+		if oldest == nil || g.CreatedAt.Before(oldest.CreatedAt) {
+			oldest = g
+		}
+	}
+	return oldest, oldest != nil
+}
+
+// Matchmake atomically finds the oldest waiting game missing role and joins
+// it, or creates a fresh default game and assigns role to it if none is
+// available. It holds e.mu for the whole scan-then-join/create so two
+// concurrent matchmake calls can't both claim the same seat.
+func (e *Engine) Matchmake(role string, defaultTimeLimit, defaultModulesCount, defaultMaxStrikes int, defaultPlayMode PlayMode, defaultStageSize int, defaultDifficulty Difficulty, playerToken string) (*Game, error) {
+	if role != "bomb" && role != "expert" {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var oldest *Game
+	for _, g := range e.games {
+		if g.State != StateLobby || !roleIsOpen(g, role) {
+			continue
+		}
+		if oldest == nil || g.CreatedAt.Before(oldest.CreatedAt) {
+			oldest = g
+		}
+	}
+
+	if oldest == nil {
+		game, err := e.createGameLocked(defaultTimeLimit, defaultModulesCount, defaultMaxStrikes, defaultPlayMode, defaultStageSize, defaultDifficulty, playerToken)
+		if err != nil {
+			return nil, err
+		}
+		oldest = game
+	}
+
+	oldest.mu.Lock()
+	e.assignRoleLocked(oldest, role)
+	gs := snapshotOf(oldest)
+	oldest.mu.Unlock()
+	e.saveSnapshot(gs)
+	_ = e.history.RecordPlayerJoin(GameHistoryPlayer{GameID: oldest.ID, Role: role, JoinedAt: time.Now()})
+	e.recordHistoryEvent(oldest.ID, EventPlayerJoined, "", role)
+
+	e.emitEvent(GameEvent{
+		Type:      EventPlayerJoined,
+		GameID:    oldest.ID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"role": role,
+		},
+	})
+
+	return oldest, nil
+}
+
+// roleIsOpen reports whether g still has a free seat for role.
+func roleIsOpen(g *Game, role string) bool {
+	switch role {
+	case "bomb":
+		return !g.BombConnected
+	case "expert":
+		return !g.ExpertConnected
+	default:
+		return false
+	}
+}
+
+// assignRoleLocked marks role as connected on g. Callers must hold g.mu.
+func (e *Engine) assignRoleLocked(g *Game, role string) {
+	switch role {
+	case "bomb":
+		g.BombConnected = true
+	case "expert":
+		g.ExpertConnected = true
+	}
+}
+
+// runLobbyJanitor periodically reaps lobbies that never finished filling up,
+// so the open-game pool used by Matchmake doesn't accumulate abandoned games.
+func (e *Engine) runLobbyJanitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		e.mu.Lock()
+		for gameID, g := range e.games {
+			g.mu.RLock()
+			stale := g.State == StateLobby && !(g.BombConnected && g.ExpertConnected) && time.Since(g.CreatedAt) > StaleLobbyTimeout
+			code := g.Code
+			g.mu.RUnlock()
+
+			if stale {
+				delete(e.games, gameID)
+				delete(e.codeIndex, code)
+				_ = e.store.DeleteGame(gameID)
+			}
+		}
+		e.mu.Unlock()
+	}
+}
+
 // GetGame returns a game by ID
 func (e *Engine) GetGame(gameID string) (*Game, bool) {
 	e.mu.RLock()
@@ -139,6 +722,10 @@ func (e *Engine) JoinGame(code string, role string) (*Game, error) {
 		return nil, fmt.Errorf("invalid role: %s", role)
 	}
 
+	e.saveSnapshot(snapshotOf(game))
+	_ = e.history.RecordPlayerJoin(GameHistoryPlayer{GameID: gameID, Role: role, JoinedAt: time.Now()})
+	e.recordHistoryEvent(gameID, EventPlayerJoined, "", role)
+
 	e.emitEvent(GameEvent{
 		Type:      EventPlayerJoined,
 		GameID:    gameID,
@@ -151,6 +738,282 @@ func (e *Engine) JoinGame(code string, role string) (*Game, error) {
 	return game, nil
 }
 
+// JoinAsSpectator looks up a game by its join code for a spectator to watch.
+// Unlike JoinGame it never claims a role seat and works for a game in any
+// state, so a spectator can drop in on a lobby still filling up or a
+// defusal already underway; the handler layer connects the returned game's
+// WebSocket room under role "spectator" to receive redacted snapshots.
+func (e *Engine) JoinAsSpectator(code string) (*Game, error) {
+	e.mu.RLock()
+	gameID, ok := e.codeIndex[code]
+	if !ok {
+		e.mu.RUnlock()
+		return nil, fmt.Errorf("game not found with code: %s", code)
+	}
+	game, ok := e.games[gameID]
+	e.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("game not found")
+	}
+
+	e.emitEvent(GameEvent{
+		Type:      EventSpectatorJoined,
+		GameID:    gameID,
+		Timestamp: time.Now(),
+	})
+
+	return game, nil
+}
+
+// GameSummary is a lightweight, spectator-safe snapshot of one game, as
+// returned by ListGames for a live game browser.
+type GameSummary struct {
+	GameID        string    `json:"game_id"`
+	Code          string    `json:"code"`
+	State         GameState `json:"state"`
+	TimeLeft      int       `json:"time_left"`
+	Strikes       int       `json:"strikes"`
+	MaxStrikes    int       `json:"max_strikes"`
+	ModulesSolved int       `json:"modules_solved"`
+	ModulesCount  int       `json:"modules_count"`
+}
+
+// ListGames returns a summary of every game currently in progress, for a
+// live game-browser / spectator lobby. Lobbies still filling up and games
+// that have already ended are omitted, since there's nothing to watch.
+func (e *Engine) ListGames() []GameSummary {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	summaries := make([]GameSummary, 0, len(e.games))
+	for _, g := range e.games {
+		g.mu.RLock()
+		if g.State == StatePlaying || g.State == StatePaused {
+			summaries = append(summaries, GameSummary{
+				GameID:        g.ID,
+				Code:          g.Code,
+				State:         g.State,
+				TimeLeft:      g.TimeLeft,
+				Strikes:       g.Strikes,
+				MaxStrikes:    g.MaxStrikes,
+				ModulesSolved: countSolvedModules(g.Modules),
+				ModulesCount:  g.ModulesCount,
+			})
+		}
+		g.mu.RUnlock()
+	}
+	return summaries
+}
+
+func countSolvedModules(modules []Module) int {
+	count := 0
+	for _, m := range modules {
+		if m.State == ModuleStateSolved {
+			count++
+		}
+	}
+	return count
+}
+
+// SetReady marks role as ready (or unready) in gameID's lobby and emits
+// player_ready/player_unready so the other side's lobby screen can update.
+func (e *Engine) SetReady(gameID, role string, ready bool) (*Game, error) {
+	e.mu.RLock()
+	game, ok := e.games[gameID]
+	e.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("game not found")
+	}
+
+	game.mu.Lock()
+	if game.State != StateLobby {
+		game.mu.Unlock()
+		return nil, fmt.Errorf("game not in lobby state")
+	}
+
+	switch role {
+	case "bomb":
+		if !game.BombConnected {
+			game.mu.Unlock()
+			return nil, fmt.Errorf("bomb not connected")
+		}
+		game.BombReady = ready
+	case "expert":
+		if !game.ExpertConnected {
+			game.mu.Unlock()
+			return nil, fmt.Errorf("expert not connected")
+		}
+		game.ExpertReady = ready
+	default:
+		game.mu.Unlock()
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+	game.mu.Unlock()
+
+	eventType := EventPlayerUnready
+	if ready {
+		eventType = EventPlayerReady
+	}
+	e.emitEvent(GameEvent{
+		Type:      eventType,
+		GameID:    gameID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"role": role,
+		},
+	})
+
+	return game, nil
+}
+
+// Leave removes role from gameID, freeing its seat for a new player. Unlike
+// HandleDisconnect, this is an explicit client request, so it takes effect
+// immediately with no grace period.
+func (e *Engine) Leave(gameID, role string) (*Game, error) {
+	e.mu.RLock()
+	game, ok := e.games[gameID]
+	e.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("game not found")
+	}
+
+	e.cancelDisconnectTimer(gameID, role)
+
+	game.mu.Lock()
+	switch role {
+	case "bomb":
+		game.BombConnected = false
+		game.BombReady = false
+	case "expert":
+		game.ExpertConnected = false
+		game.ExpertReady = false
+	default:
+		game.mu.Unlock()
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+	game.mu.Unlock()
+
+	e.emitEvent(GameEvent{
+		Type:      EventPlayerLeft,
+		GameID:    gameID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"role":     role,
+			"explicit": true,
+		},
+	})
+
+	return game, nil
+}
+
+// HandleDisconnect is called from the WebSocket unregister path when a
+// role's connection drops unexpectedly. It emits player_left immediately and
+// starts a grace timer; if the role hasn't reconnected via HandleReconnect by
+// the time it fires, an in-progress game auto-pauses so neither side strikes
+// out or runs out the clock while the other is offline.
+func (e *Engine) HandleDisconnect(gameID, role string) {
+	e.mu.RLock()
+	game, ok := e.games[gameID]
+	e.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	e.emitEvent(GameEvent{
+		Type:      EventPlayerLeft,
+		GameID:    gameID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"role":         role,
+			"explicit":     false,
+			"grace_period": DisconnectGraceTimeout.Seconds(),
+		},
+	})
+
+	key := disconnectKey(gameID, role)
+	timer := time.AfterFunc(DisconnectGraceTimeout, func() {
+		e.disconnectTimersMu.Lock()
+		delete(e.disconnectTimers, key)
+		e.disconnectTimersMu.Unlock()
+
+		e.pauseForDisconnect(game, gameID, role)
+	})
+
+	e.disconnectTimersMu.Lock()
+	if existing, ok := e.disconnectTimers[key]; ok {
+		existing.Stop()
+	}
+	e.disconnectTimers[key] = timer
+	e.disconnectTimersMu.Unlock()
+}
+
+// HandleReconnect cancels role's pending auto-pause timer if one was
+// running and, only then, emits player_reconnected so clients waiting on the
+// other end know to resume. It reports whether role actually had a pending
+// disconnect, so callers can tell a reconnect apart from a fresh join.
+func (e *Engine) HandleReconnect(gameID, role string) bool {
+	if !e.cancelDisconnectTimer(gameID, role) {
+		return false
+	}
+
+	e.emitEvent(GameEvent{
+		Type:      EventPlayerReconnected,
+		GameID:    gameID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"role": role,
+		},
+	})
+	return true
+}
+
+// cancelDisconnectTimer stops and forgets role's pending auto-pause timer,
+// reporting whether one was actually pending.
+func (e *Engine) cancelDisconnectTimer(gameID, role string) bool {
+	key := disconnectKey(gameID, role)
+	e.disconnectTimersMu.Lock()
+	defer e.disconnectTimersMu.Unlock()
+	timer, ok := e.disconnectTimers[key]
+	if !ok {
+		return false
+	}
+	timer.Stop()
+	delete(e.disconnectTimers, key)
+	return true
+}
+
+// pauseForDisconnect moves an in-progress game to StatePaused once role's
+// disconnect grace period expires without a reconnect.
+func (e *Engine) pauseForDisconnect(game *Game, gameID, role string) {
+	game.mu.Lock()
+	if game.State != StatePlaying {
+		game.mu.Unlock()
+		return
+	}
+	game.State = StatePaused
+	gs := snapshotOf(game)
+	game.mu.Unlock()
+	e.saveSnapshot(gs)
+
+	e.emitEvent(GameEvent{
+		Type:      EventGamePaused,
+		GameID:    gameID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"reason": "role_disconnected",
+			"role":   role,
+		},
+	})
+}
+
+func disconnectKey(gameID, role string) string {
+	return gameID + ":" + role
+}
+
 // StartGame starts the game timer
 func (e *Engine) StartGame(gameID string) error {
 	e.mu.Lock()
@@ -172,20 +1035,51 @@ func (e *Engine) StartGame(gameID string) error {
 		return fmt.Errorf("bomb not connected")
 	}
 
+	if !game.BombReady || !game.ExpertReady {
+		return fmt.Errorf("both roles must be ready")
+	}
+
 	now := time.Now()
 	game.State = StatePlaying
 	game.StartedAt = &now
 	game.ActiveModuleIndex = 0
 
-	// Set first module as active, rest as inactive
-	for i := range game.Modules {
-		if i == 0 {
+	e.replayLogMu.Lock()
+	e.replayStartedAt[gameID] = now
+	e.replayLogMu.Unlock()
+
+	// Activate whichever modules PlayMode says should start live.
+	switch game.PlayMode {
+	case ModeParallel:
+		for i := range game.Modules {
 			game.Modules[i].State = ModuleStateActive
-		} else {
-			game.Modules[i].State = ModuleStateInactive
+		}
+	case ModeStages:
+		stageSize := game.StageSize
+		if stageSize <= 0 {
+			stageSize = len(game.Modules)
+		}
+		for i := range game.Modules {
+			if i < stageSize {
+				game.Modules[i].State = ModuleStateActive
+			} else {
+				game.Modules[i].State = ModuleStateInactive
+			}
+		}
+	default: // ModeSequential
+		for i := range game.Modules {
+			if i == 0 {
+				game.Modules[i].State = ModuleStateActive
+			} else {
+				game.Modules[i].State = ModuleStateInactive
+			}
 		}
 	}
 
+	e.saveSnapshot(snapshotOf(game))
+	e.recordHistory(snapshotOf(game))
+	e.recordHistoryEvent(gameID, EventGameStarted, "", "")
+
 	e.emitEvent(GameEvent{
 		Type:      EventGameStarted,
 		GameID:    gameID,
@@ -230,7 +1124,12 @@ func (e *Engine) runTimer(gameID string) {
 			game.State = StateLose
 			now := time.Now()
 			game.EndedAt = &now
+			gs := snapshotOf(game)
 			game.mu.Unlock()
+			e.evictActionLimiter(gameID)
+			e.saveSnapshot(gs)
+			e.recordHistory(gs)
+			e.recordHistoryEvent(gameID, EventGameLost, "", "time_expired")
 
 			e.emitEvent(GameEvent{
 				Type:      EventGameLost,
@@ -244,8 +1143,15 @@ func (e *Engine) runTimer(gameID string) {
 		}
 
 		timeLeft := game.TimeLeft
+		strikes := game.Strikes
+		moduleViews := redactModules(game.Modules)
+		if timeLeft%snapshotTickEvery == 0 {
+			e.saveSnapshot(snapshotOf(game))
+		}
 		game.mu.Unlock()
 
+		e.UpdateStabilityState(gameID)
+
 		e.emitEvent(GameEvent{
 			Type:      EventTimerTick,
 			GameID:    gameID,
@@ -254,9 +1160,30 @@ func (e *Engine) runTimer(gameID string) {
 				"time_left": timeLeft,
 			},
 		})
+
+		e.emitEvent(GameEvent{
+			Type:      EventSpectatorSnapshot,
+			GameID:    gameID,
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"time_left": timeLeft,
+				"strikes":   strikes,
+				"modules":   moduleViews,
+			},
+		})
 	}
 }
 
+// redactModules converts modules to their spectator-safe PublicModuleView
+// form, stripping every Solution.
+func redactModules(modules []Module) []PublicModuleView {
+	views := make([]PublicModuleView, len(modules))
+	for i, m := range modules {
+		views[i] = NewPublicModuleView(m)
+	}
+	return views
+}
+
 // ProcessAction processes a player action on a module
 func (e *Engine) ProcessAction(gameID, moduleID, action string, value interface{}) (*ActionResult, error) {
 	e.mu.RLock()
@@ -267,32 +1194,52 @@ func (e *Engine) ProcessAction(gameID, moduleID, action string, value interface{
 		return nil, fmt.Errorf("game not found")
 	}
 
-	game.mu.Lock()
-	defer game.mu.Unlock()
+	if !e.actionLimiterFor(gameID).Allow() {
+		return nil, ErrActionRateLimited
+	}
 
+	game.mu.RLock()
 	if game.State != StatePlaying {
+		game.mu.RUnlock()
 		return nil, fmt.Errorf("game not in playing state")
 	}
 
-	// Find module
-	var module *Module
+	moduleIndex := -1
 	for i := range game.Modules {
 		if game.Modules[i].ID == moduleID {
-			module = &game.Modules[i]
+			moduleIndex = i
 			break
 		}
 	}
+	game.mu.RUnlock()
 
-	if module == nil {
+	if moduleIndex == -1 {
 		return nil, fmt.Errorf("module not found: %s", moduleID)
 	}
 
+	// Resolving the action only needs exclusivity over this one module, so
+	// the bomb client acting on two different modules at once doesn't
+	// serialize behind a single game.mu.Lock.
+	moduleLock := game.moduleLockFor(moduleIndex)
+	moduleLock.Lock()
+	module := &game.Modules[moduleIndex]
 	if module.State != ModuleStateActive {
+		moduleLock.Unlock()
 		return nil, fmt.Errorf("module not active")
 	}
-
-	// Process action based on module type
 	result := e.processModuleAction(game, module, action, value)
+	moduleType := module.Type
+	moduleLock.Unlock()
+
+	game.mu.RLock()
+	playerToken := game.PlayerToken
+	game.mu.RUnlock()
+	e.profiles.RecordOutcome(playerToken, moduleType, result.Strike)
+
+	entry := e.appendActionLog(gameID, moduleID, action, value, result)
+	if e.OnAction != nil {
+		e.OnAction(entry)
+	}
 
 	// Emit action event
 	e.emitEvent(GameEvent{
@@ -308,9 +1255,16 @@ func (e *Engine) ProcessAction(gameID, moduleID, action string, value interface{
 		},
 	})
 
+	// Strikes, module-solved bookkeeping, stage unlocks and win/lose all
+	// touch shared Game state, so they're serialized through game.mu even
+	// though the action resolution above wasn't.
+	game.mu.Lock()
+	defer game.mu.Unlock()
+
 	// Check if strike occurred
 	if result.Strike {
 		game.Strikes++
+		e.recordHistoryEvent(gameID, EventStrike, moduleID, result.Message)
 		e.emitEvent(GameEvent{
 			Type:      EventStrike,
 			GameID:    gameID,
@@ -327,6 +1281,8 @@ func (e *Engine) ProcessAction(gameID, moduleID, action string, value interface{
 			game.State = StateLose
 			now := time.Now()
 			game.EndedAt = &now
+			e.evictActionLimiter(gameID)
+			e.recordHistoryEvent(gameID, EventGameLost, "", "max_strikes")
 
 			e.emitEvent(GameEvent{
 				Type:      EventGameLost,
@@ -342,13 +1298,24 @@ func (e *Engine) ProcessAction(gameID, moduleID, action string, value interface{
 	// Check if module was solved
 	if result.Solved {
 		module.State = ModuleStateSolved
+		_ = e.history.RecordModule(GameHistoryModule{GameID: gameID, ModuleID: moduleID, Type: moduleType, Solved: true})
 
-		// Activate next module (sequential play)
+		// Only ModeSequential has a single "next" module; in ModeParallel
+		// every module was already active, and ModeStages unlocks a whole
+		// group at once via maybeUnlockNextStage below.
 		nextModuleID := ""
-		if game.ActiveModuleIndex+1 < len(game.Modules) {
-			game.ActiveModuleIndex++
-			game.Modules[game.ActiveModuleIndex].State = ModuleStateActive
-			nextModuleID = game.Modules[game.ActiveModuleIndex].ID
+		var stageUnlocked []string
+		switch game.PlayMode {
+		case ModeParallel:
+			// nothing to activate; every module is already active
+		case ModeStages:
+			stageUnlocked = e.maybeUnlockNextStage(game)
+		default: // ModeSequential
+			if game.ActiveModuleIndex+1 < len(game.Modules) {
+				game.ActiveModuleIndex++
+				game.Modules[game.ActiveModuleIndex].State = ModuleStateActive
+				nextModuleID = game.Modules[game.ActiveModuleIndex].ID
+			}
 		}
 
 		e.emitEvent(GameEvent{
@@ -362,11 +1329,24 @@ func (e *Engine) ProcessAction(gameID, moduleID, action string, value interface{
 			},
 		})
 
+		if len(stageUnlocked) > 0 {
+			e.emitEvent(GameEvent{
+				Type:      EventStageUnlocked,
+				GameID:    gameID,
+				Timestamp: time.Now(),
+				Data: map[string]interface{}{
+					"module_ids": stageUnlocked,
+				},
+			})
+		}
+
 		// Check if all modules are solved
 		if e.allModulesSolved(game) {
 			game.State = StateWin
 			now := time.Now()
 			game.EndedAt = &now
+			e.evictActionLimiter(gameID)
+			e.recordHistoryEvent(gameID, EventGameWon, "", "")
 
 			e.emitEvent(GameEvent{
 				Type:      EventGameWon,
@@ -379,6 +1359,10 @@ func (e *Engine) ProcessAction(gameID, moduleID, action string, value interface{
 		}
 	}
 
+	gs := snapshotOf(game)
+	e.saveSnapshot(gs)
+	e.recordHistory(gs)
+
 	return result, nil
 }
 
@@ -401,6 +1385,8 @@ func (e *Engine) processModuleAction(game *Game, module *Module, action string,
 		return e.processSimonAction(module, action, value)
 	case ModuleMagnet:
 		return e.processMagnetAction(module, action, value)
+	case ModuleStability:
+		return e.processStabilityAction(module, action, value)
 	default:
 		return &ActionResult{Success: false, Message: "unknown module type"}
 	}
@@ -490,6 +1476,21 @@ func (e *Engine) processWiresAction(module *Module, action string, value interfa
 	}
 }
 
+// configInt reads key from m as an int, accepting both a plain int (a
+// freshly-generated Module, straight out of rules.go) and a float64 (a
+// Module rehydrated from a Store's JSON snapshot, since encoding/json
+// decodes all numbers into interface{} as float64).
+func configInt(m map[string]interface{}, key string) (int, bool) {
+	switch v := m[key].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
 // processKeypadAction handles code entry
 func (e *Engine) processKeypadAction(module *Module, action string, value interface{}) *ActionResult {
 	if action != "enter_digit" && action != "submit_code" {
@@ -508,7 +1509,7 @@ func (e *Engine) processKeypadAction(module *Module, action string, value interf
 		}
 
 		currentCode, _ := module.Config["current_code"].(string)
-		codeLength, _ := module.Config["code_length"].(int)
+		codeLength, _ := configInt(module.Config, "code_length")
 		if codeLength == 0 {
 			codeLength = 3
 		}
@@ -591,10 +1592,7 @@ func (e *Engine) processSimonAction(module *Module, action string, value interfa
 		}
 	}
 
-	currentIndex, _ := module.Config["current_index"].(int)
-	if currentIndexF, ok := module.Config["current_index"].(float64); ok {
-		currentIndex = int(currentIndexF)
-	}
+	currentIndex, _ := configInt(module.Config, "current_index")
 
 	if currentIndex >= len(expectedColors) {
 		return &ActionResult{Success: false, Message: "sequence complete"}
@@ -660,6 +1658,81 @@ func (e *Engine) processMagnetAction(module *Module, action string, value interf
 	}
 }
 
+// stabilityJerkThreshold converts a module's Sensitivity (1-10, higher is
+// more tolerant) into the jerk magnitude that counts as "too much movement".
+func stabilityJerkThreshold(sensitivity int) float64 {
+	if sensitivity <= 0 {
+		sensitivity = 1
+	}
+	return float64(sensitivity) * 2.0
+}
+
+// processStabilityAction consumes one accelerometer sample streamed by the
+// bomb client as action="tilt_sample", value={x,y,z,timestamp}. It maintains
+// a one-sample rolling window to compute jerk (the rate of change of
+// acceleration magnitude): too much jerk during the "unsafe" phase is a
+// strike, while riding out the full "hold" phase below the jerk threshold
+// solves the module. UpdateStabilityState drives which phase is active.
+func (e *Engine) processStabilityAction(module *Module, action string, value interface{}) *ActionResult {
+	if action != "tilt_sample" {
+		return &ActionResult{Success: false, Message: "invalid action"}
+	}
+
+	sample, ok := value.(map[string]interface{})
+	if !ok {
+		return &ActionResult{Success: false, Message: "invalid sample"}
+	}
+
+	x, _ := sample["x"].(float64)
+	y, _ := sample["y"].(float64)
+	z, _ := sample["z"].(float64)
+	timestamp, _ := sample["timestamp"].(float64)
+
+	magnitude := math.Sqrt(x*x + y*y + z*z)
+
+	var jerk, dt float64
+	if lastMagnitude, ok := module.Config["last_magnitude"].(float64); ok {
+		if lastTimestamp, ok := module.Config["last_timestamp"].(float64); ok && timestamp > lastTimestamp {
+			dt = timestamp - lastTimestamp
+			jerk = math.Abs(magnitude-lastMagnitude) / dt
+		}
+	}
+	module.Config["last_magnitude"] = magnitude
+	module.Config["last_timestamp"] = timestamp
+	module.Config["jerk"] = jerk
+
+	sensitivity, _ := configInt(module.Config, "sensitivity")
+	threshold := stabilityJerkThreshold(sensitivity)
+	tooMuchMovement := jerk > threshold
+	module.Config["tilt_detected"] = tooMuchMovement
+
+	phase, _ := module.Config["phase"].(string)
+
+	if phase == stabilityPhaseUnsafe {
+		if tooMuchMovement {
+			return &ActionResult{Success: true, Strike: true, Message: "too much movement during unsafe phase"}
+		}
+		return &ActionResult{Success: true, Message: "steady"}
+	}
+
+	// phase == stabilityPhaseHold
+	if tooMuchMovement {
+		module.Config["hold_elapsed"] = 0.0
+		return &ActionResult{Success: true, Strike: true, Message: "movement broke the hold"}
+	}
+
+	holdSeconds, _ := configInt(module.Config, "hold_seconds")
+	elapsed, _ := module.Config["hold_elapsed"].(float64)
+	elapsed += dt
+	module.Config["hold_elapsed"] = elapsed
+
+	if elapsed >= float64(holdSeconds) {
+		return &ActionResult{Success: true, Solved: true, Message: "bomb held steady"}
+	}
+
+	return &ActionResult{Success: true, Message: "holding steady"}
+}
+
 // allModulesSolved checks if all solvable modules are solved
 func (e *Engine) allModulesSolved(game *Game) bool {
 	for _, module := range game.Modules {
@@ -670,6 +1743,37 @@ func (e *Engine) allModulesSolved(game *Game) bool {
 	return true
 }
 
+// maybeUnlockNextStage activates the next StageSize group of modules once
+// every currently-active module has been solved. Callers must hold
+// game.mu. Returns the IDs of whatever modules were just unlocked: nil if
+// the current stage isn't finished yet, or an empty slice if there's nothing
+// left to unlock.
+func (e *Engine) maybeUnlockNextStage(game *Game) []string {
+	for _, module := range game.Modules {
+		if module.State == ModuleStateActive {
+			return nil
+		}
+	}
+
+	stageSize := game.StageSize
+	if stageSize <= 0 {
+		stageSize = len(game.Modules)
+	}
+
+	unlocked := make([]string, 0, stageSize)
+	for i := range game.Modules {
+		if game.Modules[i].State != ModuleStateInactive {
+			continue
+		}
+		game.Modules[i].State = ModuleStateActive
+		unlocked = append(unlocked, game.Modules[i].ID)
+		if len(unlocked) == stageSize {
+			break
+		}
+	}
+	return unlocked
+}
+
 // GetManual returns the manual for a game based on its seed
 func (e *Engine) GetManual(gameID string) (map[string][]string, error) {
 	e.mu.RLock()
@@ -680,10 +1784,35 @@ func (e *Engine) GetManual(gameID string) (map[string][]string, error) {
 		return nil, fmt.Errorf("game not found")
 	}
 
-	ruleGen := NewRuleGenerator(game.Seed)
+	game.mu.RLock()
+	difficulty := game.Difficulty
+	biasedTypes := game.BiasedTypes
+	game.mu.RUnlock()
+
+	ruleGen := NewRuleGenerator(game.Seed, difficulty, biasedTypes)
 	return ruleGen.GetFullManual(), nil
 }
 
+// GetPlayerStats returns token's PlayerProfile, for a client to inspect its
+// own per-category success/failure rate across games.
+func (e *Engine) GetPlayerStats(token string) *PlayerProfile {
+	return e.profiles.Get(token)
+}
+
+// magnetPhaseSeconds returns how long UpdateMagnetState holds each of its
+// four LED/buzzer phases before advancing, in seconds. Harder difficulties
+// shorten the window so the single "safe" phase passes by more quickly.
+func magnetPhaseSeconds(d Difficulty) int {
+	switch d {
+	case DifficultyHard:
+		return 4
+	case DifficultyExpert:
+		return 3
+	default: // DifficultyEasy, DifficultyNormal
+		return 5
+	}
+}
+
 // UpdateMagnetState updates the magnet module state (called by timer)
 func (e *Engine) UpdateMagnetState(gameID string) {
 	e.mu.RLock()
@@ -697,13 +1826,15 @@ func (e *Engine) UpdateMagnetState(gameID string) {
 	game.mu.Lock()
 	defer game.mu.Unlock()
 
+	phaseSeconds := magnetPhaseSeconds(game.Difficulty)
+
 	for i := range game.Modules {
 		if game.Modules[i].Type == ModuleMagnet && game.Modules[i].State == ModuleStateActive {
 			// Cycle through states based on time
 			timeLeft := game.TimeLeft
 
-			// Change state every 5 seconds
-			phase := (timeLeft / 5) % 4
+			// Change state every phaseSeconds
+			phase := (timeLeft / phaseSeconds) % 4
 
 			switch phase {
 			case 0:
@@ -727,6 +1858,80 @@ func (e *Engine) UpdateMagnetState(gameID string) {
 	}
 }
 
+// UpdateStabilityState cycles each active Stability module between its
+// "unsafe" and "hold" phases based on elapsed game time, the same way
+// UpdateMagnetState cycles the magnet's LED/buzzer. It emits
+// EventStabilityPhase whenever a module's phase changes so the expert can
+// see what's coming.
+func (e *Engine) UpdateStabilityState(gameID string) {
+	e.mu.RLock()
+	game, ok := e.games[gameID]
+	e.mu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	game.mu.RLock()
+	if game.State != StatePlaying {
+		game.mu.RUnlock()
+		return
+	}
+	elapsed := game.TimeLimit - game.TimeLeft
+	var activeIndices []int
+	for i := range game.Modules {
+		if game.Modules[i].Type == ModuleStability && game.Modules[i].State == ModuleStateActive {
+			activeIndices = append(activeIndices, i)
+		}
+	}
+	game.mu.RUnlock()
+
+	// Each module's Config is guarded by its own lock (the same one
+	// ProcessAction takes around processStabilityAction), not game.mu, so
+	// this doesn't race with a bomb client mid-sample on the same module.
+	for _, i := range activeIndices {
+		lock := game.moduleLockFor(i)
+		lock.Lock()
+		module := &game.Modules[i]
+
+		unsafeSeconds, _ := configInt(module.Solution, "unsafe_seconds")
+		holdSeconds, _ := configInt(module.Config, "hold_seconds")
+		cycle := unsafeSeconds + holdSeconds
+		if cycle <= 0 {
+			lock.Unlock()
+			continue
+		}
+
+		newPhase := stabilityPhaseUnsafe
+		if elapsed%cycle >= unsafeSeconds {
+			newPhase = stabilityPhaseHold
+		}
+
+		oldPhase, _ := module.Config["phase"].(string)
+		if newPhase == oldPhase {
+			lock.Unlock()
+			continue
+		}
+
+		module.Config["phase"] = newPhase
+		if newPhase == stabilityPhaseHold {
+			module.Config["hold_elapsed"] = 0.0
+		}
+		moduleID := module.ID
+		lock.Unlock()
+
+		e.emitEvent(GameEvent{
+			Type:      EventStabilityPhase,
+			GameID:    gameID,
+			ModuleID:  moduleID,
+			Timestamp: time.Now(),
+			Data: map[string]interface{}{
+				"phase": newPhase,
+			},
+		})
+	}
+}
+
 // CleanupGame removes a game from memory
 func (e *Engine) CleanupGame(gameID string) {
 	e.mu.Lock()
@@ -736,6 +1941,21 @@ func (e *Engine) CleanupGame(gameID string) {
 		delete(e.codeIndex, game.Code)
 		delete(e.games, gameID)
 	}
+	e.evictActionLimiter(gameID)
+	_ = e.store.DeleteGame(gameID)
+
+	e.actionLogMu.Lock()
+	delete(e.actionLog, gameID)
+	delete(e.actionSeq, gameID)
+	e.actionLogMu.Unlock()
+
+	e.replayLogMu.Lock()
+	delete(e.replayLog, gameID)
+	delete(e.replayStartedAt, gameID)
+	e.replayLogMu.Unlock()
+
+	e.cancelDisconnectTimer(gameID, "bomb")
+	e.cancelDisconnectTimer(gameID, "expert")
 }
 
 // Helper functions
@@ -764,6 +1984,10 @@ func (e *Engine) generateSeed() int64 {
 }
 
 func (e *Engine) emitEvent(event GameEvent) {
+	e.appendReplayLog(event)
+	if event.Type == EventGameWon || event.Type == EventGameLost {
+		e.persistReplay(event.GameID)
+	}
 	if e.OnGameEvent != nil {
 		e.OnGameEvent(event)
 	}
@@ -0,0 +1,107 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+// errNoHistoryStore is returned by NoopHistoryStore's read methods so a
+// handler can tell "no match history configured" apart from "no rows found".
+var errNoHistoryStore = errors.New("no history store configured")
+
+// GameHistoryRecord is the persisted, queryable summary of one game, kept
+// current by HistoryStore.RecordGame on every lifecycle transition so
+// GetGameHistory/ListGameHistory can answer "what happened in this match"
+// long after CleanupGame has dropped it from the in-memory Engine and its
+// Store snapshot.
+type GameHistoryRecord struct {
+	ID           string
+	Code         string
+	Seed         int64
+	TimeLimit    int
+	MaxStrikes   int
+	PlayMode     PlayMode
+	StageSize    int
+	Difficulty   Difficulty
+	PlayerToken  string
+	State        GameState
+	Strikes      int
+	TimeLeft     int
+	ModulesCount int
+	CreatedAt    time.Time
+	StartedAt    *time.Time
+	EndedAt      *time.Time
+}
+
+// GameHistoryPlayer is one role's join record for a game.
+type GameHistoryPlayer struct {
+	GameID   string
+	Role     string
+	JoinedAt time.Time
+}
+
+// GameHistoryModule is the final recorded state of one module in a game, for
+// the /game/:id detail endpoint.
+type GameHistoryModule struct {
+	GameID   string
+	ModuleID string
+	Type     ModuleType
+	Solved   bool
+}
+
+// GameHistoryEvent is one notable lifecycle event (creation, join, strike,
+// win/lose) on a game's timeline. It's coarser than the per-action log
+// Engine.OnAction already persists; this is a match summary, not a replay.
+type GameHistoryEvent struct {
+	GameID    string
+	Type      string
+	ModuleID  string
+	Message   string
+	CreatedAt time.Time
+}
+
+// GameHistory is the full detail returned for a single match: its summary
+// record, every player that joined, every module's final state, and its
+// lifecycle event timeline.
+type GameHistory struct {
+	Game    GameHistoryRecord
+	Players []GameHistoryPlayer
+	Modules []GameHistoryModule
+	Events  []GameHistoryEvent
+}
+
+// HistoryStore persists normalized match history - distinct from Store,
+// which only keeps the latest snapshot needed to resume a still-playing
+// game and is dropped by CleanupGame once a game ends. HistoryStore is
+// append-mostly and backs the /game/history and /game/:id endpoints, so a
+// finished match stays queryable indefinitely. A *sqlc.Queries would also
+// satisfy this role directly; HistoryStore exists so Engine doesn't have to
+// depend on generated code to get there.
+type HistoryStore interface {
+	RecordGame(rec GameHistoryRecord) error
+	RecordPlayerJoin(p GameHistoryPlayer) error
+	RecordModule(m GameHistoryModule) error
+	RecordEvent(ev GameHistoryEvent) error
+
+	GetGameHistory(gameID string) (GameHistory, error)
+	ListGameHistory(limit, offset int) ([]GameHistoryRecord, error)
+}
+
+// NoopHistoryStore is the default HistoryStore: it discards every record, so
+// Engine behaves exactly as it did before match history existed until a
+// handler calls SetHistoryStore with a persistent implementation (e.g.
+// SQLHistoryStore).
+type NoopHistoryStore struct{}
+
+func (NoopHistoryStore) RecordGame(GameHistoryRecord) error       { return nil }
+func (NoopHistoryStore) RecordPlayerJoin(GameHistoryPlayer) error { return nil }
+func (NoopHistoryStore) RecordModule(GameHistoryModule) error     { return nil }
+func (NoopHistoryStore) RecordEvent(GameHistoryEvent) error       { return nil }
+
+func (NoopHistoryStore) GetGameHistory(gameID string) (GameHistory, error) {
+	return GameHistory{}, errNoHistoryStore
+}
+
+func (NoopHistoryStore) ListGameHistory(limit, offset int) ([]GameHistoryRecord, error) {
+	return nil, errNoHistoryStore
+}
@@ -0,0 +1,176 @@
+package game
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLHistoryStore persists match history in the games/players/modules/events
+// tables created by db/migrations/00001_game_history.sql. It's the
+// repository-interface alternative to wiring a *sqlc.Queries directly into
+// Engine: same role, implemented by hand against plain database/sql so
+// SetHistoryStore works without depending on generated code.
+type SQLHistoryStore struct {
+	db *sql.DB
+}
+
+// NewSQLHistoryStore opens path (typically the same SQLite file main.go
+// already runs goose migrations against) and returns a store ready for
+// SetHistoryStore.
+func NewSQLHistoryStore(path string) (*SQLHistoryStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open history store: %w", err)
+	}
+	return &SQLHistoryStore{db: db}, nil
+}
+
+// Close releases the underlying database handle.
+func (s *SQLHistoryStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLHistoryStore) RecordGame(rec GameHistoryRecord) error {
+	_, err := s.db.Exec(
+		`INSERT INTO games (id, code, seed, time_limit, max_strikes, play_mode, stage_size, difficulty, player_token, state, strikes, time_left, modules_count, created_at, started_at, ended_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+			state      = excluded.state,
+			strikes    = excluded.strikes,
+			time_left  = excluded.time_left,
+			started_at = excluded.started_at,
+			ended_at   = excluded.ended_at`,
+		rec.ID, rec.Code, rec.Seed, rec.TimeLimit, rec.MaxStrikes, string(rec.PlayMode), rec.StageSize, string(rec.Difficulty), rec.PlayerToken, string(rec.State), rec.Strikes, rec.TimeLeft, rec.ModulesCount, rec.CreatedAt, rec.StartedAt, rec.EndedAt,
+	)
+	return err
+}
+
+func (s *SQLHistoryStore) RecordPlayerJoin(p GameHistoryPlayer) error {
+	_, err := s.db.Exec(
+		`INSERT INTO players (game_id, role, joined_at) VALUES (?, ?, ?)
+		 ON CONFLICT(game_id, role) DO UPDATE SET joined_at = excluded.joined_at`,
+		p.GameID, p.Role, p.JoinedAt,
+	)
+	return err
+}
+
+func (s *SQLHistoryStore) RecordModule(m GameHistoryModule) error {
+	_, err := s.db.Exec(
+		`INSERT INTO modules (game_id, module_id, type, solved) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(game_id, module_id) DO UPDATE SET solved = excluded.solved`,
+		m.GameID, m.ModuleID, string(m.Type), m.Solved,
+	)
+	return err
+}
+
+func (s *SQLHistoryStore) RecordEvent(ev GameHistoryEvent) error {
+	_, err := s.db.Exec(
+		`INSERT INTO events (game_id, type, module_id, message, created_at) VALUES (?, ?, ?, ?, ?)`,
+		ev.GameID, ev.Type, ev.ModuleID, ev.Message, ev.CreatedAt,
+	)
+	return err
+}
+
+func (s *SQLHistoryStore) GetGameHistory(gameID string) (GameHistory, error) {
+	var h GameHistory
+	var playMode, difficulty, state string
+
+	row := s.db.QueryRow(
+		`SELECT id, code, seed, time_limit, max_strikes, play_mode, stage_size, difficulty, player_token, state, strikes, time_left, modules_count, created_at, started_at, ended_at
+		 FROM games WHERE id = ?`,
+		gameID,
+	)
+	if err := row.Scan(
+		&h.Game.ID, &h.Game.Code, &h.Game.Seed, &h.Game.TimeLimit, &h.Game.MaxStrikes, &playMode, &h.Game.StageSize,
+		&difficulty, &h.Game.PlayerToken, &state, &h.Game.Strikes, &h.Game.TimeLeft, &h.Game.ModulesCount,
+		&h.Game.CreatedAt, &h.Game.StartedAt, &h.Game.EndedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return GameHistory{}, fmt.Errorf("game not found: %s", gameID)
+		}
+		return GameHistory{}, err
+	}
+	h.Game.PlayMode = PlayMode(playMode)
+	h.Game.Difficulty = Difficulty(difficulty)
+	h.Game.State = GameState(state)
+
+	playerRows, err := s.db.Query(`SELECT game_id, role, joined_at FROM players WHERE game_id = ?`, gameID)
+	if err != nil {
+		return GameHistory{}, err
+	}
+	defer playerRows.Close()
+	for playerRows.Next() {
+		var p GameHistoryPlayer
+		if err := playerRows.Scan(&p.GameID, &p.Role, &p.JoinedAt); err != nil {
+			return GameHistory{}, err
+		}
+		h.Players = append(h.Players, p)
+	}
+	if err := playerRows.Err(); err != nil {
+		return GameHistory{}, err
+	}
+
+	moduleRows, err := s.db.Query(`SELECT game_id, module_id, type, solved FROM modules WHERE game_id = ?`, gameID)
+	if err != nil {
+		return GameHistory{}, err
+	}
+	defer moduleRows.Close()
+	for moduleRows.Next() {
+		var m GameHistoryModule
+		var modType string
+		if err := moduleRows.Scan(&m.GameID, &m.ModuleID, &modType, &m.Solved); err != nil {
+			return GameHistory{}, err
+		}
+		m.Type = ModuleType(modType)
+		h.Modules = append(h.Modules, m)
+	}
+	if err := moduleRows.Err(); err != nil {
+		return GameHistory{}, err
+	}
+
+	eventRows, err := s.db.Query(`SELECT game_id, type, module_id, message, created_at FROM events WHERE game_id = ? ORDER BY id`, gameID)
+	if err != nil {
+		return GameHistory{}, err
+	}
+	defer eventRows.Close()
+	for eventRows.Next() {
+		var ev GameHistoryEvent
+		if err := eventRows.Scan(&ev.GameID, &ev.Type, &ev.ModuleID, &ev.Message, &ev.CreatedAt); err != nil {
+			return GameHistory{}, err
+		}
+		h.Events = append(h.Events, ev)
+	}
+	return h, eventRows.Err()
+}
+
+func (s *SQLHistoryStore) ListGameHistory(limit, offset int) ([]GameHistoryRecord, error) {
+	rows, err := s.db.Query(
+		`SELECT id, code, seed, time_limit, max_strikes, play_mode, stage_size, difficulty, player_token, state, strikes, time_left, modules_count, created_at, started_at, ended_at
+		 FROM games ORDER BY created_at DESC LIMIT ? OFFSET ?`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []GameHistoryRecord
+	for rows.Next() {
+		var rec GameHistoryRecord
+		var playMode, difficulty, state string
+		if err := rows.Scan(
+			&rec.ID, &rec.Code, &rec.Seed, &rec.TimeLimit, &rec.MaxStrikes, &playMode, &rec.StageSize,
+			&difficulty, &rec.PlayerToken, &state, &rec.Strikes, &rec.TimeLeft, &rec.ModulesCount,
+			&rec.CreatedAt, &rec.StartedAt, &rec.EndedAt,
+		); err != nil {
+			return nil, err
+		}
+		rec.PlayMode = PlayMode(playMode)
+		rec.Difficulty = Difficulty(difficulty)
+		rec.State = GameState(state)
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,95 @@
+package game
+
+import (
+	"sort"
+	"sync"
+)
+
+// PlayerProfile tracks one client token's success/failure rate per
+// ModuleType across every game they've played, so future games can bias
+// module selection toward the categories they struggle with most.
+type PlayerProfile struct {
+	Token    string             `json:"token"`
+	Attempts map[ModuleType]int `json:"attempts"`
+	Failures map[ModuleType]int `json:"failures"`
+}
+
+// FailureRate returns modType's strike-per-attempt rate for this player, or 0
+// if they've never attempted it.
+func (p *PlayerProfile) FailureRate(modType ModuleType) float64 {
+	attempts := p.Attempts[modType]
+	if attempts == 0 {
+		return 0
+	}
+	return float64(p.Failures[modType]) / float64(attempts)
+}
+
+// WeakestTypes returns up to n module types this player has attempted at
+// least once, ordered from highest to lowest failure rate.
+func (p *PlayerProfile) WeakestTypes(n int) []ModuleType {
+	types := make([]ModuleType, 0, len(p.Attempts))
+	for modType := range p.Attempts {
+		types = append(types, modType)
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		return p.FailureRate(types[i]) > p.FailureRate(types[j])
+	})
+
+	if n < len(types) {
+		types = types[:n]
+	}
+	return types
+}
+
+// ProfileStore holds every player's PlayerProfile, keyed by client token.
+// Safe for concurrent use across many games' goroutines at once.
+type ProfileStore struct {
+	mu       sync.RWMutex
+	profiles map[string]*PlayerProfile
+}
+
+// NewProfileStore creates an empty ProfileStore.
+func NewProfileStore() *ProfileStore {
+	return &ProfileStore{profiles: make(map[string]*PlayerProfile)}
+}
+
+// Get returns token's profile, creating an empty one if this is its first
+// appearance.
+func (s *ProfileStore) Get(token string) *PlayerProfile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getLocked(token)
+}
+
+// getLocked returns (creating if necessary) token's profile. Callers must
+// already hold s.mu.
+func (s *ProfileStore) getLocked(token string) *PlayerProfile {
+	profile, ok := s.profiles[token]
+	if !ok {
+		profile = &PlayerProfile{
+			Token:    token,
+			Attempts: make(map[ModuleType]int),
+			Failures: make(map[ModuleType]int),
+		}
+		s.profiles[token] = profile
+	}
+	return profile
+}
+
+// RecordOutcome logs one module action's outcome against token's profile: an
+// attempt on modType, and a failure if the action produced a strike.
+func (s *ProfileStore) RecordOutcome(token string, modType ModuleType, strike bool) {
+	if token == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile := s.getLocked(token)
+	profile.Attempts[modType]++
+	if strike {
+		profile.Failures[modType]++
+	}
+}
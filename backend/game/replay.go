@@ -0,0 +1,130 @@
+package game
+
+// Replayer reconstructs a game deterministically from its seed and a
+// recorded ReplayEntry stream, re-running the same rule generation and
+// action processing the live Engine used. RuleGenerator and
+// processModuleAction are both pure functions of their inputs, so replaying
+// the same stream against the same seed always yields byte-identical
+// module Config snapshots and the same final outcome - the basis for
+// bug-report reproduction and tournament result verification.
+type Replayer struct {
+	engine *Engine
+}
+
+// NewReplayer creates a Replayer. It only needs an *Engine to reuse the
+// unexported action-resolution logic (processModuleAction, allModulesSolved),
+// not any live game state, so the zero-value Engine is always sufficient.
+func NewReplayer() *Replayer {
+	return &Replayer{engine: &Engine{}}
+}
+
+// Replay reconstructs the game that produced entries, given the settings it
+// was created with, and returns it in whatever terminal state (StateWin,
+// StateLose, or still StatePlaying if entries never reach an outcome) the
+// action stream resolves to. difficulty and biasedTypes must match the
+// original game's, since they affect RuleGenerator's output; playMode and
+// stageSize must match too, since they decide which modules start active
+// and how later ones unlock (see activateInitial and the ModeStages case
+// below, mirroring StartGame and ProcessAction's own activation logic).
+func (rp *Replayer) Replay(seed int64, timeLimit, modulesCount, maxStrikes int, playMode PlayMode, stageSize int, difficulty Difficulty, biasedTypes []ModuleType, entries []ReplayEntry) *Game {
+	ruleGen := NewRuleGenerator(seed, difficulty, biasedTypes)
+
+	g := &Game{
+		State:        StatePlaying,
+		Seed:         seed,
+		TimeLimit:    timeLimit,
+		TimeLeft:     timeLimit,
+		MaxStrikes:   maxStrikes,
+		Modules:      ruleGen.GenerateModules(modulesCount),
+		ModulesCount: modulesCount,
+		PlayMode:     playMode,
+		StageSize:    stageSize,
+	}
+	rp.activateInitial(g)
+
+	for _, entry := range entries {
+		if entry.Type != EventModuleAction || g.State != StatePlaying {
+			continue
+		}
+
+		module := rp.findModule(g, entry.ModuleID)
+		if module == nil || module.State != ModuleStateActive {
+			continue
+		}
+
+		action, _ := entry.Data["action"].(string)
+		result := rp.engine.processModuleAction(g, module, action, entry.Data["value"])
+
+		if result.Strike {
+			g.Strikes++
+			if g.Strikes >= g.MaxStrikes {
+				g.State = StateLose
+				continue
+			}
+		}
+
+		if result.Solved {
+			module.State = ModuleStateSolved
+
+			switch g.PlayMode {
+			case ModeParallel:
+				// nothing to activate; every module was already active
+			case ModeStages:
+				rp.engine.maybeUnlockNextStage(g)
+			default: // ModeSequential
+				if g.ActiveModuleIndex+1 < len(g.Modules) {
+					g.ActiveModuleIndex++
+					g.Modules[g.ActiveModuleIndex].State = ModuleStateActive
+				}
+			}
+
+			if rp.engine.allModulesSolved(g) {
+				g.State = StateWin
+			}
+		}
+	}
+
+	return g
+}
+
+// activateInitial puts g's modules into the same initial active/inactive
+// split StartGame would have produced for g.PlayMode, so a ModeParallel or
+// ModeStages replay doesn't silently stall with every action dropped as
+// "module not active" - the same switch StartGame uses.
+func (rp *Replayer) activateInitial(g *Game) {
+	switch g.PlayMode {
+	case ModeParallel:
+		for i := range g.Modules {
+			g.Modules[i].State = ModuleStateActive
+		}
+	case ModeStages:
+		stageSize := g.StageSize
+		if stageSize <= 0 {
+			stageSize = len(g.Modules)
+		}
+		for i := range g.Modules {
+			if i < stageSize {
+				g.Modules[i].State = ModuleStateActive
+			} else {
+				g.Modules[i].State = ModuleStateInactive
+			}
+		}
+	default: // ModeSequential
+		for i := range g.Modules {
+			if i == 0 {
+				g.Modules[i].State = ModuleStateActive
+			} else {
+				g.Modules[i].State = ModuleStateInactive
+			}
+		}
+	}
+}
+
+func (rp *Replayer) findModule(g *Game, moduleID string) *Module {
+	for i := range g.Modules {
+		if g.Modules[i].ID == moduleID {
+			return &g.Modules[i]
+		}
+	}
+	return nil
+}
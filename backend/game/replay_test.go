@@ -0,0 +1,119 @@
+package game
+
+import "testing"
+
+// solveModule returns the action/value pairs that solve module outright,
+// derived from its own Solution, for the two module types this test uses.
+func solveModule(t *testing.T, module Module) []struct {
+	action string
+	value  interface{}
+} {
+	switch module.Type {
+	case ModuleWires:
+		correctCuts, _ := module.Solution["correct_cuts"].([]int)
+		if len(correctCuts) == 0 {
+			t.Fatalf("wires module %s has no correct_cuts", module.ID)
+		}
+		return []struct {
+			action string
+			value  interface{}
+		}{{"cut_wire", float64(correctCuts[0])}}
+	case ModuleKeypad:
+		correctCode, _ := module.Solution["correct_code"].(string)
+		if correctCode == "" {
+			t.Fatalf("keypad module %s has no correct_code", module.ID)
+		}
+		actions := make([]struct {
+			action string
+			value  interface{}
+		}, 0, len(correctCode)+1)
+		for _, digit := range correctCode {
+			actions = append(actions, struct {
+				action string
+				value  interface{}
+			}{"enter_digit", string(digit)})
+		}
+		actions = append(actions, struct {
+			action string
+			value  interface{}
+		}{"submit_code", nil})
+		return actions
+	default:
+		t.Fatalf("solveModule doesn't support module type %s", module.Type)
+		return nil
+	}
+}
+
+// TestReplayDeterminism plays a real game to completion through the live
+// Engine, then replays its own recorded event log through a fresh Replayer
+// and checks the two reach the same outcome - the guarantee replay.go's
+// doc comment claims. Run for both ModeSequential (activates one module at
+// a time) and ModeParallel (activates every module up front), since Replay
+// only mirrored StartGame's ModeSequential activation until this test
+// caught the gap.
+func TestReplayDeterminism(t *testing.T) {
+	for _, mode := range []PlayMode{ModeSequential, ModeParallel} {
+		t.Run(string(mode), func(t *testing.T) {
+			e := NewEngine(1000, 1000)
+
+			g, err := e.CreateGame(300, 2, 3, mode, 0, DifficultyEasy, "")
+			if err != nil {
+				t.Fatalf("CreateGame: %v", err)
+			}
+
+			g.mu.Lock()
+			// CreateGame picks among all 5 module types at random (there's no
+			// seed override hook), and solveModule below only knows how to
+			// solve Wires/Keypad. Regenerate this game's modules from its own
+			// seed, but bias toward Wires/Keypad the same way a player's
+			// weakest categories would: GenerateModules always puts biased
+			// types first, so with a 2-module game this is deterministic
+			// regardless of what the random seed rolled.
+			// ReconstructGame regenerates from g.BiasedTypes, so record the
+			// bias there too.
+			g.BiasedTypes = []ModuleType{ModuleWires, ModuleKeypad}
+			g.Modules = NewRuleGenerator(g.Seed, DifficultyEasy, g.BiasedTypes).GenerateModules(len(g.Modules))
+			g.BombConnected = true
+			g.ExpertConnected = true
+			g.BombReady = true
+			g.ExpertReady = true
+			modules := append([]Module(nil), g.Modules...)
+			g.mu.Unlock()
+
+			if err := e.StartGame(g.ID); err != nil {
+				t.Fatalf("StartGame: %v", err)
+			}
+
+			// ModeSequential only accepts actions on modules[0] until it's
+			// solved, so solving in slice order matches activation order
+			// under every PlayMode.
+			for _, module := range modules {
+				for _, act := range solveModule(t, module) {
+					if _, err := e.ProcessAction(g.ID, module.ID, act.action, act.value); err != nil {
+						t.Fatalf("ProcessAction(%s, %s): %v", module.ID, act.action, err)
+					}
+				}
+			}
+
+			g.mu.RLock()
+			liveState, liveStrikes := g.State, g.Strikes
+			g.mu.RUnlock()
+
+			if liveState != StateWin {
+				t.Fatalf("live game ended in %s, want %s", liveState, StateWin)
+			}
+
+			replayed, err := e.ReconstructGame(g.ID)
+			if err != nil {
+				t.Fatalf("ReconstructGame: %v", err)
+			}
+
+			if replayed.State != liveState {
+				t.Errorf("replayed state = %s, want %s", replayed.State, liveState)
+			}
+			if replayed.Strikes != liveStrikes {
+				t.Errorf("replayed strikes = %d, want %d", replayed.Strikes, liveStrikes)
+			}
+		})
+	}
+}
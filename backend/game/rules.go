@@ -7,13 +7,41 @@ import (
 
 // RuleGenerator generates deterministic rules based on a seed
 type RuleGenerator struct {
-	rng *rand.Rand
+	rng        *rand.Rand
+	difficulty Difficulty
+	weakTypes  map[ModuleType]bool // Module types to bias toward, from the player's PlayerProfile
 }
 
-// NewRuleGenerator creates a new rule generator with the given seed
-func NewRuleGenerator(seed int64) *RuleGenerator {
+// NewRuleGenerator creates a new rule generator with the given seed,
+// difficulty and (optionally empty) set of module types the player is
+// weakest at, used to bias which modules get picked when count is less than
+// the number of module types.
+func NewRuleGenerator(seed int64, difficulty Difficulty, weakTypes []ModuleType) *RuleGenerator {
+	weak := make(map[ModuleType]bool, len(weakTypes))
+	for _, t := range weakTypes {
+		weak[t] = true
+	}
+
 	return &RuleGenerator{
-		rng: rand.New(rand.NewSource(seed)),
+		rng:        rand.New(rand.NewSource(seed)),
+		difficulty: difficulty,
+		weakTypes:  weak,
+	}
+}
+
+// difficultyFactor maps Difficulty onto a 0-3 scale used to scale sequence
+// lengths and code lengths: 0 on DifficultyEasy up to 3 on DifficultyExpert,
+// with DifficultyNormal (and any unrecognized value) at 1.
+func (r *RuleGenerator) difficultyFactor() int {
+	switch r.difficulty {
+	case DifficultyEasy:
+		return 0
+	case DifficultyHard:
+		return 2
+	case DifficultyExpert:
+		return 3
+	default: // DifficultyNormal
+		return 1
 	}
 }
 
@@ -22,13 +50,17 @@ func (r *RuleGenerator) GenerateModules(count int) []Module {
 	modules := make([]Module, 0, count)
 
 	moduleTypes := []ModuleType{
-		ModuleWires,  // Cut wires in correct order
-		ModuleSimon,  // Color sequence - expert taps on mobile
-		ModuleMagnet, // Timing puzzle - apply magnet at right moment
+		ModuleWires,     // Cut wires in correct order
+		ModuleKeypad,    // Enter a numeric code
+		ModuleSimon,     // Color sequence - expert taps on mobile
+		ModuleMagnet,    // Timing puzzle - apply magnet at right moment
+		ModuleStability, // Keep the bomb still through a hold phase
 	}
 
-	// Shuffle module order
+	// Shuffle module order, then move the player's weakest categories to the
+	// front so they're favored when count trims the list down.
 	r.shuffleModuleTypes(moduleTypes)
+	r.prioritizeWeakTypes(moduleTypes)
 
 	// Take requested number of modules
 	for i := 0; i < count && i < len(moduleTypes); i++ {
@@ -47,14 +79,41 @@ func (r *RuleGenerator) shuffleModuleTypes(types []ModuleType) {
 	}
 }
 
+// prioritizeWeakTypes moves whatever's in r.weakTypes to the front of types,
+// preserving their relative shuffled order, so a game with fewer modules
+// than module types is biased toward the categories this player fails most.
+// A no-op if the player has no recorded weak types yet.
+func (r *RuleGenerator) prioritizeWeakTypes(types []ModuleType) {
+	if len(r.weakTypes) == 0 {
+		return
+	}
+
+	reordered := make([]ModuleType, 0, len(types))
+	for _, t := range types {
+		if r.weakTypes[t] {
+			reordered = append(reordered, t)
+		}
+	}
+	for _, t := range types {
+		if !r.weakTypes[t] {
+			reordered = append(reordered, t)
+		}
+	}
+	copy(types, reordered)
+}
+
 func (r *RuleGenerator) generateModule(id string, modType ModuleType) Module {
 	switch modType {
 	case ModuleWires:
 		return r.generateWiresModule(id)
+	case ModuleKeypad:
+		return r.generateKeypadModule(id)
 	case ModuleSimon:
 		return r.generateSimonModule(id)
 	case ModuleMagnet:
 		return r.generateMagnetModule(id)
+	case ModuleStability:
+		return r.generateStabilityModule(id)
 	default:
 		return Module{ID: id, Type: modType, State: ModuleStateActive}
 	}
@@ -88,10 +147,18 @@ func (r *RuleGenerator) generateWiresModule(id string) Module {
 	}
 }
 
-// generateEnabledWires randomly decides which wires are present (2-4 wires)
+// generateEnabledWires randomly decides which wires are present. Harder
+// difficulties raise the floor so the minimum is 3 wires on Hard and always
+// 4 on Expert, instead of the 2-wire minimum on Easy/Normal.
 func (r *RuleGenerator) generateEnabledWires() []bool {
-	// Always have at least 2 wires, up to 4
-	numWires := 2 + r.rng.Intn(3) // 2, 3, or 4 wires
+	minWires := 2
+	switch r.difficulty {
+	case DifficultyHard:
+		minWires = 3
+	case DifficultyExpert:
+		minWires = 4
+	}
+	numWires := minWires + r.rng.Intn(5-minWires) // minWires..4 wires
 
 	enabled := []bool{false, false, false, false}
 	indices := []int{0, 1, 2, 3}
@@ -110,6 +177,46 @@ func (r *RuleGenerator) generateEnabledWires() []bool {
 	return enabled
 }
 
+// generateKeypadModule creates a Keypad module: the expert reads the correct
+// code off the manual and the defuser enters it digit by digit. Code length
+// scales with difficulty, from 3 digits on Easy up to 6 on Expert.
+func (r *RuleGenerator) generateKeypadModule(id string) Module {
+	correctCode, codeLength := r.generateKeypadCode()
+
+	config := map[string]interface{}{
+		"display_code": formatCodeDisplay("", codeLength),
+		"current_code": "",
+		"code_length":  codeLength,
+	}
+
+	solution := map[string]interface{}{
+		"correct_code": correctCode,
+	}
+
+	return Module{
+		ID:       id,
+		Type:     ModuleKeypad,
+		State:    ModuleStateActive,
+		Config:   config,
+		Solution: solution,
+	}
+}
+
+// generateKeypadCode rolls a numeric code and its length, scaling length
+// with difficulty from 3 digits on Easy up to 6 on Expert. It's shared by
+// generateKeypadModule and GetKeypadManual so the manual can consume RNG the
+// same way and report the same code the module was created with.
+func (r *RuleGenerator) generateKeypadCode() (code string, length int) {
+	const digits = "0123456789"
+	length = 3 + r.difficultyFactor() // 3-6 digits
+
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = digits[r.rng.Intn(len(digits))]
+	}
+	return string(b), length
+}
+
 // determineCorrectWire applies rules based on which wires are enabled
 // Positions: 0=Red, 1=Blue, 2=Green, 3=Yellow
 func (r *RuleGenerator) determineCorrectWire(wireEnabled []bool) int {
@@ -167,7 +274,7 @@ func (r *RuleGenerator) determineCorrectWire(wireEnabled []bool) int {
 // Pi shows colors on RGB LED, Expert taps matching colors on mobile app
 func (r *RuleGenerator) generateSimonModule(id string) Module {
 	colors := []string{"red", "green", "blue"}
-	sequenceLength := 3 + r.rng.Intn(2) // 3-4 colors
+	sequenceLength := 3 + r.rng.Intn(2) + r.difficultyFactor() // 3-4 colors, longer on harder difficulties
 
 	sequence := make([]string, sequenceLength)
 
@@ -219,6 +326,37 @@ func (r *RuleGenerator) generateMagnetModule(id string) Module {
 	}
 }
 
+// generateStabilityModule creates a Stability module: an unsafe phase where
+// jerking the bomb causes a strike, followed by a hold phase that solves the
+// module once it's held steady for the full duration. UpdateStabilityState
+// cycles the two phases the same way UpdateMagnetState cycles the magnet's
+// LED/buzzer, driven off the game clock rather than randomly.
+func (r *RuleGenerator) generateStabilityModule(id string) Module {
+	sensitivity := 3 + r.rng.Intn(6)   // 3-8, higher means more jerk-tolerant
+	holdSeconds := 5 + r.rng.Intn(6)   // 5-10s of required stillness
+	unsafeSeconds := 4 + r.rng.Intn(5) // 4-8s unsafe window before each hold
+
+	config := map[string]interface{}{
+		"sensitivity":   sensitivity,
+		"phase":         stabilityPhaseUnsafe,
+		"hold_seconds":  holdSeconds,
+		"hold_elapsed":  0.0,
+		"tilt_detected": false,
+	}
+
+	solution := map[string]interface{}{
+		"unsafe_seconds": unsafeSeconds,
+	}
+
+	return Module{
+		ID:       id,
+		Type:     ModuleStability,
+		State:    ModuleStateActive,
+		Config:   config,
+		Solution: solution,
+	}
+}
+
 // GetWiresManual returns the manual/instructions for the Wires module
 // Rules are puzzles that the Expert must solve based on which wires the Defuser sees
 func (r *RuleGenerator) GetWiresManual() []string {
@@ -325,6 +463,21 @@ func (r *RuleGenerator) GetSimonManual() []string {
 	}
 }
 
+// GetKeypadManual returns the manual/instructions for the Keypad module,
+// including the correct code itself - unlike the wires and Simon modules,
+// nothing about the keypad's solution is visible to the defuser, so the
+// expert must read it straight out of the manual.
+func (r *RuleGenerator) GetKeypadManual() []string {
+	// Consume RNG the same way generateKeypadModule would, to stay in sync
+	code, _ := r.generateKeypadCode()
+
+	return []string{
+		fmt.Sprintf("The keypad's correct code is: %s", code),
+		"Tell the defuser to enter it one digit at a time, then submit.",
+		"An incorrect submission clears the code and causes a strike.",
+	}
+}
+
 // GetMagnetManual returns the manual/instructions for the Magnet module
 func (r *RuleGenerator) GetMagnetManual() []string {
 	return []string{
@@ -337,6 +490,16 @@ func (r *RuleGenerator) GetMagnetManual() []string {
 	}
 }
 
+// GetStabilityManual returns the manual/instructions for the Stability module
+func (r *RuleGenerator) GetStabilityManual() []string {
+	return []string{
+		"The bomb's accelerometer reports a current phase: UNSAFE or HOLD.",
+		"During UNSAFE, keep the bomb as still as possible - jerking it causes a strike.",
+		"During HOLD, hold the bomb steady for the full duration to solve the module.",
+		"Any sudden movement during HOLD resets the hold timer.",
+	}
+}
+
 // GetFullManual returns the complete manual for all modules
 // Must generate in the same order as GenerateModules to keep RNG in sync
 func (r *RuleGenerator) GetFullManual() map[string][]string {
@@ -345,22 +508,31 @@ func (r *RuleGenerator) GetFullManual() map[string][]string {
 	// Reproduce the same module order as GenerateModules
 	moduleTypes := []ModuleType{
 		ModuleWires,
+		ModuleKeypad,
 		ModuleSimon,
 		ModuleMagnet,
+		ModuleStability,
 	}
 
-	// Shuffle in same way as GenerateModules
+	// Shuffle and bias in the same way as GenerateModules
 	r.shuffleModuleTypes(moduleTypes)
+	r.prioritizeWeakTypes(moduleTypes)
 
 	// Generate manuals in the same order as modules were generated
 	for _, modType := range moduleTypes {
 		switch modType {
 		case ModuleWires:
 			manual["wires"] = r.GetWiresManual()
+		case ModuleKeypad:
+			manual["keypad"] = r.GetKeypadManual()
 		case ModuleSimon:
+			r.generateSimonModule("") // consume RNG the same way GenerateModules does
 			manual["simon"] = r.GetSimonManual()
 		case ModuleMagnet:
 			manual["magnet"] = r.GetMagnetManual()
+		case ModuleStability:
+			r.generateStabilityModule("") // consume RNG the same way GenerateModules does
+			manual["stability"] = r.GetStabilityManual()
 		}
 	}
 
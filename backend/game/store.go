@@ -0,0 +1,162 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+// GameSnapshot is the serializable form of a Game, persisted by a Store
+// after every state transition so an Engine restart can rehydrate
+// in-flight games instead of losing them to an in-memory map.
+type GameSnapshot struct {
+	ID                string       `json:"id"`
+	Code              string       `json:"code"`
+	State             GameState    `json:"state"`
+	Seed              int64        `json:"seed"`
+	TimeLimit         int          `json:"time_limit"`
+	TimeLeft          int          `json:"time_left"`
+	Strikes           int          `json:"strikes"`
+	MaxStrikes        int          `json:"max_strikes"`
+	Modules           []Module     `json:"modules"`
+	ModulesCount      int          `json:"modules_count"`
+	PlayMode          PlayMode     `json:"play_mode"`
+	StageSize         int          `json:"stage_size,omitempty"`
+	ActiveModuleIndex int          `json:"active_module_index"`
+	Difficulty        Difficulty   `json:"difficulty"`
+	PlayerToken       string       `json:"player_token,omitempty"`
+	BiasedTypes       []ModuleType `json:"biased_types,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+	StartedAt         *time.Time   `json:"started_at,omitempty"`
+	EndedAt           *time.Time   `json:"ended_at,omitempty"`
+	BombConnected     bool         `json:"bomb_connected"`
+	ExpertConnected   bool         `json:"expert_connected"`
+	BombReady         bool         `json:"bomb_ready"`
+	ExpertReady       bool         `json:"expert_ready"`
+}
+
+// snapshotOf copies g's current state into a GameSnapshot. It takes no lock
+// of its own; callers must already hold g.mu (for reading or writing).
+func snapshotOf(g *Game) GameSnapshot {
+	return GameSnapshot{
+		ID:                g.ID,
+		Code:              g.Code,
+		State:             g.State,
+		Seed:              g.Seed,
+		TimeLimit:         g.TimeLimit,
+		TimeLeft:          g.TimeLeft,
+		Strikes:           g.Strikes,
+		MaxStrikes:        g.MaxStrikes,
+		Modules:           append([]Module(nil), g.Modules...),
+		ModulesCount:      g.ModulesCount,
+		PlayMode:          g.PlayMode,
+		StageSize:         g.StageSize,
+		ActiveModuleIndex: g.ActiveModuleIndex,
+		Difficulty:        g.Difficulty,
+		PlayerToken:       g.PlayerToken,
+		BiasedTypes:       append([]ModuleType(nil), g.BiasedTypes...),
+		CreatedAt:         g.CreatedAt,
+		StartedAt:         g.StartedAt,
+		EndedAt:           g.EndedAt,
+		BombConnected:     g.BombConnected,
+		ExpertConnected:   g.ExpertConnected,
+		BombReady:         g.BombReady,
+		ExpertReady:       g.ExpertReady,
+	}
+}
+
+// restoreGame rebuilds a *Game from a snapshot, ready to be reinserted into
+// Engine.games.
+func restoreGame(gs GameSnapshot) *Game {
+	return &Game{
+		ID:                gs.ID,
+		Code:              gs.Code,
+		State:             gs.State,
+		Seed:              gs.Seed,
+		TimeLimit:         gs.TimeLimit,
+		TimeLeft:          gs.TimeLeft,
+		Strikes:           gs.Strikes,
+		MaxStrikes:        gs.MaxStrikes,
+		Modules:           gs.Modules,
+		ModulesCount:      gs.ModulesCount,
+		PlayMode:          gs.PlayMode,
+		StageSize:         gs.StageSize,
+		ActiveModuleIndex: gs.ActiveModuleIndex,
+		Difficulty:        gs.Difficulty,
+		PlayerToken:       gs.PlayerToken,
+		BiasedTypes:       gs.BiasedTypes,
+		CreatedAt:         gs.CreatedAt,
+		StartedAt:         gs.StartedAt,
+		EndedAt:           gs.EndedAt,
+		BombConnected:     gs.BombConnected,
+		ExpertConnected:   gs.ExpertConnected,
+		BombReady:         gs.BombReady,
+		ExpertReady:       gs.ExpertReady,
+		moduleLocks:       make([]sync.Mutex, len(gs.Modules)),
+	}
+}
+
+// Store persists game state so an Engine restart can rehydrate in-flight
+// games instead of losing them. Implementations must be safe for
+// concurrent use, since Engine calls them from many games' goroutines at
+// once.
+type Store interface {
+	// SaveGame upserts a game's current snapshot.
+	SaveGame(snapshot GameSnapshot) error
+
+	// LoadGame returns a single game's last saved snapshot.
+	LoadGame(gameID string) (snapshot GameSnapshot, found bool, err error)
+
+	// DeleteGame removes a game's snapshot once it's torn down.
+	DeleteGame(gameID string) error
+
+	// ListActive returns every snapshot still worth rehydrating on
+	// startup: anything that hadn't already reached StateWin/StateLose.
+	ListActive() ([]GameSnapshot, error)
+}
+
+// MemoryStore is the default Store: a plain in-memory map. It's what Engine
+// used implicitly before Store existed, so a restart still loses state
+// unless a persistent Store (BoltStore, SQLiteStore) is configured instead.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	games map[string]GameSnapshot
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{games: make(map[string]GameSnapshot)}
+}
+
+func (s *MemoryStore) SaveGame(snapshot GameSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.games[snapshot.ID] = snapshot
+	return nil
+}
+
+func (s *MemoryStore) LoadGame(gameID string) (GameSnapshot, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	gs, ok := s.games[gameID]
+	return gs, ok, nil
+}
+
+func (s *MemoryStore) DeleteGame(gameID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.games, gameID)
+	return nil
+}
+
+func (s *MemoryStore) ListActive() ([]GameSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	active := make([]GameSnapshot, 0, len(s.games))
+	for _, gs := range s.games {
+		if gs.State != StateWin && gs.State != StateLose {
+			active = append(active, gs)
+		}
+	}
+	return active, nil
+}
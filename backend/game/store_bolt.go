@@ -0,0 +1,100 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// gamesBucket is the single bbolt bucket BoltStore keeps every snapshot in,
+// keyed by game ID.
+var gamesBucket = []byte("games")
+
+// BoltStore persists game snapshots as JSON values in a single bbolt
+// bucket, so a server restart can rehydrate in-flight games from a local
+// file instead of losing them with the default MemoryStore.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) the bbolt database at path and
+// ensures its games bucket exists.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open bolt store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(gamesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init bolt store: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close releases the underlying bbolt file handle.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) SaveGame(snapshot GameSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).Put([]byte(snapshot.ID), data)
+	})
+}
+
+func (s *BoltStore) LoadGame(gameID string) (GameSnapshot, bool, error) {
+	var gs GameSnapshot
+	found := false
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(gamesBucket).Get([]byte(gameID))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &gs)
+	})
+	if err != nil {
+		return GameSnapshot{}, false, err
+	}
+	return gs, found, nil
+}
+
+func (s *BoltStore) DeleteGame(gameID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).Delete([]byte(gameID))
+	})
+}
+
+func (s *BoltStore) ListActive() ([]GameSnapshot, error) {
+	var active []GameSnapshot
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(gamesBucket).ForEach(func(_, data []byte) error {
+			var gs GameSnapshot
+			if err := json.Unmarshal(data, &gs); err != nil {
+				return err
+			}
+			if gs.State != StateWin && gs.State != StateLose {
+				active = append(active, gs)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return active, nil
+}
@@ -0,0 +1,108 @@
+package game
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// createGamesTableSQL mirrors the single-table, JSON-blob shape BoltStore
+// uses, so both alternative Stores share the same rehydration semantics -
+// only the storage engine differs.
+const createGamesTableSQL = `
+CREATE TABLE IF NOT EXISTS engine_games (
+	id    TEXT PRIMARY KEY,
+	state TEXT NOT NULL,
+	data  BLOB NOT NULL
+)`
+
+// SQLiteStore persists game snapshots as JSON blobs in a SQLite table, for
+// deployments that already manage a SQLite file and would rather not add a
+// second embedded database just for game state.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens path (creating it if necessary) and ensures its
+// games table exists.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite store: %w", err)
+	}
+
+	if _, err := db.Exec(createGamesTableSQL); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init sqlite store: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Close releases the underlying database handle.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) SaveGame(snapshot GameSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO engine_games (id, state, data) VALUES (?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET state = excluded.state, data = excluded.data`,
+		snapshot.ID, string(snapshot.State), data,
+	)
+	return err
+}
+
+func (s *SQLiteStore) LoadGame(gameID string) (GameSnapshot, bool, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM engine_games WHERE id = ?`, gameID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return GameSnapshot{}, false, nil
+	}
+	if err != nil {
+		return GameSnapshot{}, false, err
+	}
+
+	var gs GameSnapshot
+	if err := json.Unmarshal(data, &gs); err != nil {
+		return GameSnapshot{}, false, err
+	}
+	return gs, true, nil
+}
+
+func (s *SQLiteStore) DeleteGame(gameID string) error {
+	_, err := s.db.Exec(`DELETE FROM engine_games WHERE id = ?`, gameID)
+	return err
+}
+
+func (s *SQLiteStore) ListActive() ([]GameSnapshot, error) {
+	rows, err := s.db.Query(
+		`SELECT data FROM engine_games WHERE state NOT IN (?, ?)`,
+		string(StateWin), string(StateLose),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var active []GameSnapshot
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var gs GameSnapshot
+		if err := json.Unmarshal(data, &gs); err != nil {
+			return nil, err
+		}
+		active = append(active, gs)
+	}
+	return active, rows.Err()
+}
@@ -0,0 +1,93 @@
+package game
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestStabilityModuleSurvivesRestart exercises the SaveGame -> ListActive ->
+// restoreGame round trip Engine.Restore performs after a process restart,
+// for a Stability module paused mid-hold. BoltStore serializes a
+// GameSnapshot as JSON, which decodes every number in Config/Solution back
+// as a float64 rather than the plain int rules.go generated it as; configInt
+// must paper over that or hold_seconds/unsafe_seconds silently come back as
+// 0 (see processStabilityAction/UpdateStabilityState).
+func TestStabilityModuleSurvivesRestart(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "games.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer store.Close()
+
+	module := Module{
+		ID:    "stability-1",
+		Type:  ModuleStability,
+		State: ModuleStateActive,
+		Config: map[string]interface{}{
+			"sensitivity":   5,
+			"phase":         stabilityPhaseHold,
+			"hold_seconds":  8,
+			"hold_elapsed":  2.0,
+			"tilt_detected": false,
+		},
+		Solution: map[string]interface{}{
+			"unsafe_seconds": 6,
+		},
+	}
+
+	original := &Game{
+		ID:           "game-1",
+		Code:         "ABCDEF",
+		State:        StatePlaying,
+		TimeLimit:    300,
+		TimeLeft:     250,
+		MaxStrikes:   3,
+		Modules:      []Module{module},
+		ModulesCount: 1,
+		PlayMode:     ModeSequential,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := store.SaveGame(snapshotOf(original)); err != nil {
+		t.Fatalf("SaveGame: %v", err)
+	}
+
+	active, err := store.ListActive()
+	if err != nil {
+		t.Fatalf("ListActive: %v", err)
+	}
+	if len(active) != 1 {
+		t.Fatalf("ListActive returned %d snapshots, want 1", len(active))
+	}
+
+	restored := restoreGame(active[0])
+	restoredModule := &restored.Modules[0]
+
+	if holdSeconds, ok := configInt(restoredModule.Config, "hold_seconds"); !ok || holdSeconds != 8 {
+		t.Errorf("restored hold_seconds = %d (ok=%v), want 8", holdSeconds, ok)
+	}
+	if unsafeSeconds, ok := configInt(restoredModule.Solution, "unsafe_seconds"); !ok || unsafeSeconds != 6 {
+		t.Errorf("restored unsafe_seconds = %d (ok=%v), want 6", unsafeSeconds, ok)
+	}
+
+	// A tilt_sample with negligible jerk right after restart must not
+	// instantly solve the module. If hold_seconds had come back as 0
+	// (the bug this test guards against), elapsed (2.0) >= 0 would be
+	// true on the very first post-restart sample.
+	e := NewEngine(1000, 1000)
+	e.mu.Lock()
+	e.games[restored.ID] = restored
+	e.codeIndex[restored.Code] = restored.ID
+	e.mu.Unlock()
+
+	result, err := e.ProcessAction(restored.ID, restoredModule.ID, "tilt_sample", map[string]interface{}{
+		"x": 0.0, "y": 0.0, "z": 0.0, "timestamp": 0.1,
+	})
+	if err != nil {
+		t.Fatalf("ProcessAction: %v", err)
+	}
+	if result.Solved {
+		t.Errorf("restored stability module solved on first post-restart sample; hold_seconds likely came back as 0")
+	}
+}
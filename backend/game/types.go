@@ -11,6 +11,7 @@ type GameState string
 const (
 	StateLobby   GameState = "lobby"   // Waiting for players
 	StatePlaying GameState = "playing" // Game in progress
+	StatePaused  GameState = "paused"  // A role disconnected and didn't reconnect within the grace period
 	StateWin     GameState = "win"     // Bomb defused
 	StateLose    GameState = "lose"    // Bomb exploded
 )
@@ -26,13 +27,17 @@ const (
 	ModuleStability ModuleType = "stability"
 )
 
+// AllModuleTypes lists every module type RuleGenerator can produce.
+var AllModuleTypes = []ModuleType{ModuleWires, ModuleKeypad, ModuleSimon, ModuleMagnet, ModuleStability}
+
 // ModuleState represents the state of a module
 type ModuleState string
 
 const (
-	ModuleStateActive ModuleState = "active"
-	ModuleStateSolved ModuleState = "solved"
-	ModuleStateFailed ModuleState = "failed"
+	ModuleStateInactive ModuleState = "inactive"
+	ModuleStateActive   ModuleState = "active"
+	ModuleStateSolved   ModuleState = "solved"
+	ModuleStateFailed   ModuleState = "failed"
 )
 
 // WireColor represents wire colors for the Wires module
@@ -48,36 +53,76 @@ const (
 // AllWireColors is a list of all available wire colors
 var AllWireColors = []WireColor{WireRed, WireBlue, WireWhite, WireOrange}
 
+// PlayMode controls how a game's modules become available to work on.
+type PlayMode string
+
+const (
+	ModeSequential PlayMode = "sequential" // Modules activate one at a time, in order
+	ModeParallel   PlayMode = "parallel"   // Every module is active from the start
+	ModeStages     PlayMode = "stages"     // Modules activate in fixed-size groups
+)
+
+// Difficulty scales RuleGenerator output: harder difficulties produce longer
+// Simon sequences and keypad codes, more wires, and shorter magnet safe
+// windows. The wire cut rule sets are the same at every difficulty.
+type Difficulty string
+
+const (
+	DifficultyEasy   Difficulty = "easy"
+	DifficultyNormal Difficulty = "normal"
+	DifficultyHard   Difficulty = "hard"
+	DifficultyExpert Difficulty = "expert"
+)
+
 // Module represents a single puzzle module on the bomb
 type Module struct {
 	ID       string                 `json:"id"`
 	Type     ModuleType             `json:"type"`
 	State    ModuleState            `json:"state"`
-	Config   map[string]interface{} `json:"config"`   // Module-specific configuration
-	Solution map[string]interface{} `json:"-"`        // Correct answer (hidden from clients)
+	Config   map[string]interface{} `json:"config"`             // Module-specific configuration
+	Solution map[string]interface{} `json:"solution,omitempty"` // Correct answer; handlers.sanitizeModules strips this before a Module ever reaches a client response
 }
 
 // Game represents a single game session
 type Game struct {
-	ID           string      `json:"id"`
-	Code         string      `json:"code"` // 6-character join code
-	State        GameState   `json:"state"`
-	Seed         int64       `json:"seed"`
-	TimeLimit    int         `json:"time_limit"`    // Total time in seconds
-	TimeLeft     int         `json:"time_left"`     // Remaining time
-	Strikes      int         `json:"strikes"`       // Current strikes
-	MaxStrikes   int         `json:"max_strikes"`   // Max strikes before explosion
-	Modules      []Module    `json:"modules"`
-	ModulesCount int         `json:"modules_count"`
-	CreatedAt    time.Time   `json:"created_at"`
-	StartedAt    *time.Time  `json:"started_at,omitempty"`
-	EndedAt      *time.Time  `json:"ended_at,omitempty"`
+	ID                string       `json:"id"`
+	Code              string       `json:"code"` // 6-character join code
+	State             GameState    `json:"state"`
+	Seed              int64        `json:"seed"`
+	TimeLimit         int          `json:"time_limit"`  // Total time in seconds
+	TimeLeft          int          `json:"time_left"`   // Remaining time
+	Strikes           int          `json:"strikes"`     // Current strikes
+	MaxStrikes        int          `json:"max_strikes"` // Max strikes before explosion
+	Modules           []Module     `json:"modules"`
+	ModulesCount      int          `json:"modules_count"`
+	PlayMode          PlayMode     `json:"play_mode"`
+	StageSize         int          `json:"stage_size,omitempty"` // Modules unlocked per group in ModeStages
+	ActiveModuleIndex int          `json:"active_module_index"`  // Index into Modules of the currently active one (ModeSequential only)
+	Difficulty        Difficulty   `json:"difficulty"`
+	PlayerToken       string       `json:"-"`                      // Client token whose PlayerProfile biased/will record this game
+	BiasedTypes       []ModuleType `json:"biased_types,omitempty"` // Module types favored by the player's weakest categories at creation time
+	CreatedAt         time.Time    `json:"created_at"`
+	StartedAt         *time.Time   `json:"started_at,omitempty"`
+	EndedAt           *time.Time   `json:"ended_at,omitempty"`
 
 	// Client tracking (not serialized to JSON)
 	BombConnected   bool `json:"bomb_connected"`
 	ExpertConnected bool `json:"expert_connected"`
+	BombReady       bool `json:"bomb_ready"`
+	ExpertReady     bool `json:"expert_ready"`
 
 	mu sync.RWMutex
+
+	// moduleLocks guards each Modules[i] individually, so ProcessAction can
+	// resolve actions on two different modules at once instead of queueing
+	// behind a single mu.Lock. Sized to len(Modules) when the game is created
+	// or restored; never resized afterward.
+	moduleLocks []sync.Mutex
+}
+
+// moduleLockFor returns the dedicated mutex for Modules[i].
+func (g *Game) moduleLockFor(i int) *sync.Mutex {
+	return &g.moduleLocks[i]
 }
 
 // WiresConfig holds configuration for the Wires module
@@ -134,6 +179,33 @@ type StabilityConfig struct {
 	Sensitivity  int  `json:"sensitivity"`   // How sensitive (1-10)
 }
 
+// Stability module phases, cycled by UpdateStabilityState off the game
+// clock the same way the Magnet module's LED/buzzer cycle.
+const (
+	stabilityPhaseUnsafe = "unsafe" // Jerking the bomb now causes a strike
+	stabilityPhaseHold   = "hold"   // Must hold steady for hold_seconds to solve
+)
+
+// PublicModuleView is the redacted form of Module sent to spectators: it
+// carries everything safe to watch (type, state, config) but never
+// Solution, so following a defusal attempt live can't spoil the answer.
+type PublicModuleView struct {
+	ID     string                 `json:"id"`
+	Type   ModuleType             `json:"type"`
+	State  ModuleState            `json:"state"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// NewPublicModuleView redacts m's Solution for spectator broadcast.
+func NewPublicModuleView(m Module) PublicModuleView {
+	return PublicModuleView{
+		ID:     m.ID,
+		Type:   m.Type,
+		State:  m.State,
+		Config: m.Config,
+	}
+}
+
 // GameEvent represents an event that occurred in the game
 type GameEvent struct {
 	Type      string                 `json:"type"`
@@ -145,13 +217,22 @@ type GameEvent struct {
 
 // Event types
 const (
-	EventGameCreated    = "game_created"
-	EventPlayerJoined   = "player_joined"
-	EventGameStarted    = "game_started"
-	EventTimerTick      = "timer_tick"
-	EventModuleAction   = "module_action"
-	EventModuleSolved   = "module_solved"
-	EventStrike         = "strike"
-	EventGameWon        = "game_won"
-	EventGameLost       = "game_lost"
+	EventGameCreated       = "game_created"
+	EventPlayerJoined      = "player_joined"
+	EventPlayerReady       = "player_ready"
+	EventPlayerUnready     = "player_unready"
+	EventPlayerLeft        = "player_left"
+	EventPlayerReconnected = "player_reconnected"
+	EventGameStarted       = "game_started"
+	EventGamePaused        = "game_paused"
+	EventTimerTick         = "timer_tick"
+	EventModuleAction      = "module_action"
+	EventModuleSolved      = "module_solved"
+	EventStrike            = "strike"
+	EventGameWon           = "game_won"
+	EventGameLost          = "game_lost"
+	EventSpectatorJoined   = "spectator_joined"
+	EventSpectatorSnapshot = "spectator_snapshot"
+	EventStageUnlocked     = "stage_unlocked"
+	EventStabilityPhase    = "stability_phase"
 )
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"photon-entropy/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler exposes the server's live configuration for operators, so
+// they can confirm a SIGHUP reload actually took effect without grepping
+// logs or redeploying.
+type ConfigHandler struct {
+	config *config.Watcher
+}
+
+// NewConfigHandler creates a new config handler
+func NewConfigHandler(cfg *config.Watcher) *ConfigHandler {
+	return &ConfigHandler{config: cfg}
+}
+
+// GetConfig handles GET /api/v1/config, returning the current effective
+// config.Config as JSON (not the YAML it came from, since env overrides and
+// defaults may have changed values since config.yaml was last read).
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.config.Get())
+}
@@ -1,31 +1,56 @@
 package handlers
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/P5ina/photon-entropy/config"
-	"github.com/P5ina/photon-entropy/db/sqlc"
+	"github.com/P5ina/photon-entropy/db"
+	"github.com/P5ina/photon-entropy/deviceauth"
 	"github.com/P5ina/photon-entropy/ws"
 	"github.com/gin-gonic/gin"
 )
 
+// sessionTTL is the handshake session's heartbeat window: a device that
+// stops making authenticated requests for this long has to re-handshake.
+const sessionTTL = 5 * time.Minute
+
+var (
+	errNoSession      = errors.New("no active session for device; handshake required")
+	errDeviceMismatch = errors.New("decrypted device_id does not match session")
+)
+
 type DeviceHandler struct {
-	queries *sqlc.Queries
-	config  *config.Config
-	hub     *ws.Hub
+	queries  *db.Queries
+	config   *config.Watcher
+	hub      *ws.Hub
+	sessions *deviceauth.SessionManager
 }
 
-func NewDeviceHandler(q *sqlc.Queries, cfg *config.Config, hub *ws.Hub) *DeviceHandler {
+func NewDeviceHandler(q *db.Queries, cfg *config.Watcher, hub *ws.Hub) *DeviceHandler {
 	return &DeviceHandler{
-		queries: q,
-		config:  cfg,
-		hub:     hub,
+		queries:  q,
+		config:   cfg,
+		hub:      hub,
+		sessions: deviceauth.NewSessionManager(sessionTTL),
 	}
 }
 
+// Sessions exposes the handshake SessionManager so other handlers whose
+// requests must also be envelope-authenticated (e.g. EntropyHandler.Submit)
+// can verify against the same live sessions Handshake/UpdateStatus use,
+// instead of each handler maintaining its own disconnected session set.
+func (h *DeviceHandler) Sessions() *deviceauth.SessionManager {
+	return h.sessions
+}
+
 type DeviceStatusResponse struct {
 	DeviceID       string    `json:"device_id"`
 	IsOnline       bool      `json:"is_online"`
@@ -54,7 +79,7 @@ func (h *DeviceHandler) Status(c *gin.Context) {
 		for i, d := range devices {
 			isOnline := false
 			if d.LastSeen.Valid {
-				isOnline = time.Since(d.LastSeen.Time) < h.config.GetOfflineTimeout()
+				isOnline = time.Since(d.LastSeen.Time) < h.config.Get().GetOfflineTimeout()
 			}
 
 			responses[i] = DeviceStatusResponse{
@@ -79,7 +104,7 @@ func (h *DeviceHandler) Status(c *gin.Context) {
 
 	isOnline := false
 	if device.LastSeen.Valid {
-		isOnline = time.Since(device.LastSeen.Time) < h.config.GetOfflineTimeout()
+		isOnline = time.Since(device.LastSeen.Time) < h.config.Get().GetOfflineTimeout()
 	}
 
 	c.JSON(http.StatusOK, DeviceStatusResponse{
@@ -114,11 +139,11 @@ func (h *DeviceHandler) History(c *gin.Context) {
 		limit = 20
 	}
 
-	var commits []sqlc.Commit
+	var commits []db.Commit
 	var err error
 
 	if deviceID != "" {
-		commits, err = h.queries.GetCommitsByDevice(c, sqlc.GetCommitsByDeviceParams{
+		commits, err = h.queries.GetCommitsByDevice(c, db.GetCommitsByDeviceParams{
 			DeviceID: deviceID,
 			Limit:    int64(limit),
 		})
@@ -150,22 +175,179 @@ func (h *DeviceHandler) History(c *gin.Context) {
 	c.JSON(http.StatusOK, items)
 }
 
+// RegisterDeviceRequest registers a device's RSA public key on first boot.
+type RegisterDeviceRequest struct {
+	DeviceID  string `json:"device_id" binding:"required"`
+	PublicKey string `json:"public_key" binding:"required"` // PEM-encoded PKIX RSA public key
+}
+
+type RegisterDeviceResponse struct {
+	DeviceSecret string `json:"device_secret"`
+}
+
+// RegisterDevice handles POST /api/v1/device/register. It stores the
+// device's public key and hands back a device secret that must accompany
+// future handshake requests, so a caller who merely knows a device_id can't
+// swap in their own key for it.
+func (h *DeviceHandler) RegisterDevice(c *gin.Context) {
+	var req RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := deviceauth.ParsePublicKeyPEM([]byte(req.PublicKey)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid public_key: " + err.Error()})
+		return
+	}
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate device secret"})
+		return
+	}
+	secret := hex.EncodeToString(secretBytes)
+
+	_, err := h.queries.CreateDeviceKey(c, db.CreateDeviceKeyParams{
+		DeviceID:  req.DeviceID,
+		PublicKey: req.PublicKey,
+		Secret:    secret,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RegisterDeviceResponse{DeviceSecret: secret})
+}
+
+// HandshakeRequest starts (or restarts) an AES session for an already
+// registered device.
+type HandshakeRequest struct {
+	DeviceID     string `json:"device_id" binding:"required"`
+	DeviceSecret string `json:"device_secret" binding:"required"`
+}
+
+type HandshakeResponse struct {
+	// WrappedSessionKey is the AES-256 session key, RSA-OAEP encrypted
+	// against the device's registered public key. Only the device's
+	// private key can recover it.
+	WrappedSessionKey string `json:"wrapped_session_key"`
+}
+
+// Handshake handles POST /api/v1/device/handshake. On success the server
+// holds the plaintext session key in memory, keyed by device_id with a
+// heartbeat TTL; the device must decrypt WrappedSessionKey locally to learn
+// the same key before it can call UpdateStatus or submit entropy.
+func (h *DeviceHandler) Handshake(c *gin.Context) {
+	var req HandshakeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deviceKey, err := h.queries.GetDeviceKey(c, req.DeviceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "device not registered"})
+		return
+	}
+
+	if deviceKey.Secret != req.DeviceSecret {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid device secret"})
+		return
+	}
+
+	pub, err := deviceauth.ParsePublicKeyPEM([]byte(deviceKey.PublicKey))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "stored public key is invalid"})
+		return
+	}
+
+	sessionKey, err := deviceauth.GenerateSessionKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate session key"})
+		return
+	}
+
+	wrapped, err := deviceauth.WrapKeyForDevice(pub, sessionKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to wrap session key"})
+		return
+	}
+
+	h.sessions.Create(req.DeviceID, sessionKey)
+
+	c.JSON(http.StatusOK, HandshakeResponse{
+		WrappedSessionKey: base64.StdEncoding.EncodeToString(wrapped),
+	})
+}
+
+// EncryptedEnvelope wraps any authenticated device payload. Payload is the
+// AES-256-GCM sealed JSON body (nonce prepended), encrypted under the
+// session key negotiated in Handshake.
+type EncryptedEnvelope struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Payload  string `json:"payload" binding:"required"` // base64
+}
+
+// openEnvelope looks up the caller's session, decrypts payload and decodes
+// it into out, and rejects the request if the device_id sealed inside the
+// payload doesn't match the one the session was issued to - closing the
+// spoofing hole where a caller merely guesses another device's session map
+// key. Shared by every handler whose requests must be envelope-authenticated
+// against the same sessions (DeviceHandler.UpdateStatus, EntropyHandler.Submit).
+func openEnvelope(sessions *deviceauth.SessionManager, env EncryptedEnvelope, out interface{ GetDeviceID() string }) error {
+	session, ok := sessions.Get(env.DeviceID)
+	if !ok {
+		return errNoSession
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := deviceauth.Open(session.Key, sealed)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(plaintext, out); err != nil {
+		return err
+	}
+
+	if out.GetDeviceID() != env.DeviceID {
+		return errDeviceMismatch
+	}
+
+	sessions.Heartbeat(env.DeviceID)
+	return nil
+}
+
+func (u UpdateStatusRequest) GetDeviceID() string { return u.DeviceID }
+
 type UpdateStatusRequest struct {
 	DeviceID    string `json:"device_id" binding:"required"`
 	IsTooBright bool   `json:"is_too_bright"`
 }
 
 func (h *DeviceHandler) UpdateStatus(c *gin.Context) {
-	var req UpdateStatusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	var env EncryptedEnvelope
+	if err := c.ShouldBindJSON(&env); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	var req UpdateStatusRequest
+	if err := openEnvelope(h.sessions, env, &req); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	device, err := h.queries.GetDevice(c, req.DeviceID)
 	if err != nil {
 		// Device doesn't exist, create it
-		device, err = h.queries.UpsertDevice(c, sqlc.UpsertDeviceParams{
+		device, err = h.queries.UpsertDevice(c, db.UpsertDeviceParams{
 			ID:          req.DeviceID,
 			LastSeen:    sql.NullTime{Time: time.Now(), Valid: true},
 			IsTooBright: sql.NullInt64{Int64: boolToInt(req.IsTooBright), Valid: true},
@@ -176,7 +358,7 @@ func (h *DeviceHandler) UpdateStatus(c *gin.Context) {
 		}
 	} else {
 		// Update existing device
-		device, err = h.queries.UpsertDevice(c, sqlc.UpsertDeviceParams{
+		device, err = h.queries.UpsertDevice(c, db.UpsertDeviceParams{
 			ID:             req.DeviceID,
 			LastSeen:       sql.NullTime{Time: time.Now(), Valid: true},
 			TotalCommits:   device.TotalCommits,
@@ -191,14 +373,7 @@ func (h *DeviceHandler) UpdateStatus(c *gin.Context) {
 
 	// Broadcast via WebSocket
 	if h.hub != nil {
-		h.hub.BroadcastDeviceUpdate(
-			device.ID,
-			true,
-			time.Now().UTC(),
-			device.TotalCommits.Int64,
-			device.AverageQuality.Float64,
-			req.IsTooBright,
-		)
+		h.hub.BroadcastDeviceUpdate(device.ID, true, time.Now().UTC(), req.IsTooBright)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
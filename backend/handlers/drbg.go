@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/P5ina/photon-entropy/entropy"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// drbgSeedBytes is how much of the shared Pool each new session (and each
+// reseed) draws on - 384 bits, per SP 800-90A's minimum entropy input
+// length for a 256-bit security strength DRBG.
+const drbgSeedBytes = 48
+
+// DRBGHandler exposes the pool only as a seed source: each session gets
+// its own SP 800-90A HMAC-DRBG instance, so API consumers get
+// reproducible-per-session, forward-secure output without draining the
+// shared Pool on every request.
+type DRBGHandler struct {
+	pool *entropy.Pool
+
+	mu       sync.Mutex
+	sessions map[string]*entropy.DRBG
+}
+
+func NewDRBGHandler(p *entropy.Pool) *DRBGHandler {
+	return &DRBGHandler{
+		pool:     p,
+		sessions: make(map[string]*entropy.DRBG),
+	}
+}
+
+type InstantiateRequest struct {
+	PersonalizationString string `json:"personalization_string"`
+}
+
+type InstantiateResponse struct {
+	SessionID string `json:"session_id"`
+}
+
+// Instantiate seeds a new DRBG session from 384 bits pulled from the pool
+// plus the caller's personalization string.
+func (h *DRBGHandler) Instantiate(c *gin.Context) {
+	var req InstantiateRequest
+	_ = c.ShouldBindJSON(&req)
+
+	seed := h.pool.GetBytes(drbgSeedBytes)
+	if seed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "insufficient entropy"})
+		return
+	}
+
+	drbg := entropy.Instantiate(seed, []byte(req.PersonalizationString))
+
+	sessionID := uuid.New().String()
+	h.mu.Lock()
+	h.sessions[sessionID] = drbg
+	h.mu.Unlock()
+
+	c.JSON(http.StatusOK, InstantiateResponse{SessionID: sessionID})
+}
+
+type GenerateRequest struct {
+	AdditionalInput string `json:"additional_input"`
+}
+
+type GenerateResponse struct {
+	Bytes string `json:"bytes"`
+}
+
+// Generate returns bytes (query param "bytes") of output from the session
+// identified by the :id path param, algorithmically - no pool draw.
+func (h *DRBGHandler) Generate(c *gin.Context) {
+	drbg, ok := h.session(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown drbg session"})
+		return
+	}
+
+	numBytes, err := strconv.Atoi(c.Query("bytes"))
+	if err != nil || numBytes <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bytes must be a positive integer"})
+		return
+	}
+
+	var req GenerateRequest
+	_ = c.ShouldBindJSON(&req)
+
+	output, err := drbg.Generate(numBytes*8, []byte(req.AdditionalInput))
+	if err != nil {
+		if errors.Is(err, entropy.ErrReseedRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": "reseed required"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, GenerateResponse{Bytes: hex.EncodeToString(output)})
+}
+
+type ReseedRequest struct {
+	AdditionalInput string `json:"additional_input"`
+}
+
+// Reseed draws a fresh 384 bits from the pool and folds them into the
+// session identified by the :id path param, resetting its reseed counter.
+func (h *DRBGHandler) Reseed(c *gin.Context) {
+	drbg, ok := h.session(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown drbg session"})
+		return
+	}
+
+	var req ReseedRequest
+	_ = c.ShouldBindJSON(&req)
+
+	seed := h.pool.GetBytes(drbgSeedBytes)
+	if seed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "insufficient entropy"})
+		return
+	}
+
+	drbg.Reseed(seed, []byte(req.AdditionalInput))
+	c.JSON(http.StatusOK, gin.H{"status": "reseeded"})
+}
+
+func (h *DRBGHandler) session(id string) (*entropy.DRBG, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	drbg, ok := h.sessions[id]
+	return drbg, ok
+}
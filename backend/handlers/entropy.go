@@ -10,7 +10,8 @@ import (
 	"time"
 
 	"github.com/P5ina/photon-entropy/config"
-	"github.com/P5ina/photon-entropy/db/sqlc"
+	"github.com/P5ina/photon-entropy/db"
+	"github.com/P5ina/photon-entropy/deviceauth"
 	"github.com/P5ina/photon-entropy/entropy"
 	"github.com/P5ina/photon-entropy/verifier"
 	"github.com/P5ina/photon-entropy/ws"
@@ -19,20 +20,26 @@ import (
 )
 
 type EntropyHandler struct {
-	queries  *sqlc.Queries
-	pool     *entropy.Pool
-	verifier *verifier.Verifier
-	config   *config.Config
-	hub      *ws.Hub
+	queries   *db.Queries
+	pool      *entropy.Pool
+	verifier  *verifier.Verifier
+	health    *verifier.HealthMonitor
+	extractor entropy.Extractor
+	config    *config.Config
+	hub       *ws.Hub
+	sessions  *deviceauth.SessionManager
 }
 
-func NewEntropyHandler(q *sqlc.Queries, p *entropy.Pool, v *verifier.Verifier, cfg *config.Config, hub *ws.Hub) *EntropyHandler {
+func NewEntropyHandler(q *db.Queries, p *entropy.Pool, v *verifier.Verifier, h *verifier.HealthMonitor, ex entropy.Extractor, cfg *config.Config, hub *ws.Hub, sessions *deviceauth.SessionManager) *EntropyHandler {
 	return &EntropyHandler{
-		queries:  q,
-		pool:     p,
-		verifier: v,
-		config:   cfg,
-		hub:      hub,
+		queries:   q,
+		pool:      p,
+		verifier:  v,
+		health:    h,
+		extractor: ex,
+		config:    cfg,
+		hub:       hub,
+		sessions:  sessions,
 	}
 }
 
@@ -43,46 +50,96 @@ type SubmitRequest struct {
 	IsTooBright bool    `json:"is_too_bright"`
 }
 
+func (r SubmitRequest) GetDeviceID() string { return r.DeviceID }
+
 type SubmitResponse struct {
-	ID       string                `json:"id"`
-	Quality  float64               `json:"quality"`
-	Tests    verifier.Tests        `json:"tests"`
-	Accepted bool                  `json:"accepted"`
+	ID       string         `json:"id"`
+	Quality  float64        `json:"quality"`
+	Tests    verifier.Tests `json:"tests"`
+	Accepted bool           `json:"accepted"`
 }
 
 func (h *EntropyHandler) Submit(c *gin.Context) {
-	var req SubmitRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	var env EncryptedEnvelope
+	if err := c.ShouldBindJSON(&env); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	var req SubmitRequest
+	if err := openEnvelope(h.sessions, env, &req); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	if len(req.RawSamples) < h.config.Entropy.MinSamples {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient samples"})
 		return
 	}
 
+	health := h.health.Check(req.RawSamples)
+	if h.hub != nil {
+		h.hub.BroadcastEntropyHealth(health.Suspect, health.MinEntropy, health.Reason)
+	}
+	if !health.Passed {
+		count, _ := h.queries.CountCommitsByDevice(c, req.DeviceID)
+		avgQuality, _ := h.queries.GetAverageQualityByDevice(c, req.DeviceID)
+
+		h.queries.UpsertDevice(c, db.UpsertDeviceParams{
+			ID:             req.DeviceID,
+			LastSeen:       sql.NullTime{Time: time.Now(), Valid: true},
+			TotalCommits:   sql.NullInt64{Int64: count, Valid: true},
+			AverageQuality: sql.NullFloat64{Float64: avgQuality, Valid: true},
+			IsTooBright:    sql.NullInt64{Int64: boolToInt64(req.IsTooBright), Valid: true},
+			IsSuspect:      sql.NullInt64{Int64: 1, Valid: true},
+		})
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "health test failed", "reason": health.Reason})
+		return
+	}
+
 	result := h.verifier.Verify(req.RawSamples)
 
+	extracted, debiasedBits := h.extractor.Extract(req.RawSamples, health.MinEntropy)
+
 	commitID := uuid.New().String()
 
 	rawBytes := encodeIntSlice(req.RawSamples)
 	timestampBytes := encodeInt64Slice(req.Timestamps)
 
-	_, err := h.queries.CreateCommit(c, sqlc.CreateCommitParams{
-		ID:                    commitID,
-		DeviceID:              req.DeviceID,
-		RawSamples:            rawBytes,
-		Timestamps:            timestampBytes,
-		Quality:               result.Quality,
-		TestFrequencyPassed:   boolToInt64(result.Tests.Frequency.Passed),
-		TestFrequencyRatio:    sql.NullFloat64{Float64: result.Tests.Frequency.Value, Valid: true},
-		TestRunsPassed:        boolToInt64(result.Tests.Runs.Passed),
-		TestRunsMaxLength:     sql.NullInt64{Int64: int64(result.Tests.Runs.Value), Valid: true},
-		TestChiPassed:         boolToInt64(result.Tests.ChiSquare.Passed),
-		TestChiValue:          sql.NullFloat64{Float64: result.Tests.ChiSquare.Value, Valid: true},
-		TestVariancePassed:    boolToInt64(result.Tests.Variance.Passed),
-		TestVarianceValue:     sql.NullFloat64{Float64: result.Tests.Variance.Value, Valid: true},
+	_, err := h.queries.CreateCommit(c, db.CreateCommitParams{
+		ID:                  commitID,
+		DeviceID:            req.DeviceID,
+		RawSamples:          rawBytes,
+		Timestamps:          timestampBytes,
+		Quality:             result.Quality,
+		PreExtractionBytes:  int64(len(req.RawSamples)),
+		PostExtractionBytes: int64(len(extracted)),
+		DebiasedBits:        int64(debiasedBits),
+		TestFrequencyPassed: boolToInt64(result.Tests.Frequency.Passed),
+		TestFrequencyRatio:  sql.NullFloat64{Float64: result.Tests.Frequency.Value, Valid: true},
+		TestRunsPassed:      boolToInt64(result.Tests.Runs.Passed),
+		TestRunsMaxLength:   sql.NullInt64{Int64: int64(result.Tests.Runs.Value), Valid: true},
+		TestChiPassed:       boolToInt64(result.Tests.ChiSquare.Passed),
+		TestChiValue:        sql.NullFloat64{Float64: result.Tests.ChiSquare.Value, Valid: true},
+		TestVariancePassed:  boolToInt64(result.Tests.Variance.Passed),
+		TestVarianceValue:   sql.NullFloat64{Float64: result.Tests.Variance.Value, Valid: true},
+
+		TestMonobitFrequencyPassed:  boolToInt64(result.Tests.MonobitFrequency.Passed),
+		TestMonobitFrequencyPValue:  sql.NullFloat64{Float64: result.Tests.MonobitFrequency.PValue, Valid: true},
+		TestBlockFrequencyPassed:    boolToInt64(result.Tests.BlockFrequency.Passed),
+		TestBlockFrequencyPValue:    sql.NullFloat64{Float64: result.Tests.BlockFrequency.PValue, Valid: true},
+		TestConditionedRunsPassed:   boolToInt64(result.Tests.ConditionedRuns.Passed),
+		TestConditionedRunsPValue:   sql.NullFloat64{Float64: result.Tests.ConditionedRuns.PValue, Valid: true},
+		TestLongestRunPassed:        boolToInt64(result.Tests.LongestRun.Passed),
+		TestLongestRunPValue:        sql.NullFloat64{Float64: result.Tests.LongestRun.PValue, Valid: true},
+		TestCumulativeSumsFwdPassed: boolToInt64(result.Tests.CumulativeSumsForward.Passed),
+		TestCumulativeSumsFwdPValue: sql.NullFloat64{Float64: result.Tests.CumulativeSumsForward.PValue, Valid: true},
+		TestCumulativeSumsBwdPassed: boolToInt64(result.Tests.CumulativeSumsBackward.Passed),
+		TestCumulativeSumsBwdPValue: sql.NullFloat64{Float64: result.Tests.CumulativeSumsBackward.PValue, Valid: true},
+		TestApproxEntropyM2Passed:   boolToInt64(result.Tests.ApproxEntropyM2.Passed),
+		TestApproxEntropyM2PValue:   sql.NullFloat64{Float64: result.Tests.ApproxEntropyM2.PValue, Valid: true},
+		TestApproxEntropyM3Passed:   boolToInt64(result.Tests.ApproxEntropyM3.Passed),
+		TestApproxEntropyM3PValue:   sql.NullFloat64{Float64: result.Tests.ApproxEntropyM3.PValue, Valid: true},
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save commit"})
@@ -92,17 +149,18 @@ func (h *EntropyHandler) Submit(c *gin.Context) {
 	count, _ := h.queries.CountCommitsByDevice(c, req.DeviceID)
 	avgQuality, _ := h.queries.GetAverageQualityByDevice(c, req.DeviceID)
 
-	h.queries.UpsertDevice(c, sqlc.UpsertDeviceParams{
+	h.queries.UpsertDevice(c, db.UpsertDeviceParams{
 		ID:             req.DeviceID,
 		LastSeen:       sql.NullTime{Time: time.Now(), Valid: true},
 		TotalCommits:   sql.NullInt64{Int64: count, Valid: true},
 		AverageQuality: sql.NullFloat64{Float64: avgQuality, Valid: true},
 		IsTooBright:    sql.NullInt64{Int64: boolToInt64(req.IsTooBright), Valid: true},
+		IsSuspect:      sql.NullInt64{Int64: 0, Valid: true},
 	})
 
-	accepted := result.Quality >= h.config.Entropy.MinQuality
+	accepted := result.Quality >= h.config.Entropy.MinQuality && len(extracted) > 0
 	if accepted {
-		h.pool.Add(req.RawSamples)
+		h.pool.Add(bytesToSamples(extracted))
 	}
 
 	// Broadcast WebSocket events
@@ -126,6 +184,11 @@ type RandomResponse struct {
 }
 
 func (h *EntropyHandler) Random(c *gin.Context) {
+	if h.health.Suspect() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "entropy source failed health test"})
+		return
+	}
+
 	minVal, _ := strconv.ParseInt(c.DefaultQuery("min", "0"), 10, 64)
 	maxVal, _ := strconv.ParseInt(c.DefaultQuery("max", "100"), 10, 64)
 
@@ -153,6 +216,11 @@ type PasswordResponse struct {
 }
 
 func (h *EntropyHandler) Password(c *gin.Context) {
+	if h.health.Suspect() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "entropy source failed health test"})
+		return
+	}
+
 	length, _ := strconv.Atoi(c.DefaultQuery("length", "16"))
 	if length < 8 || length > 128 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "length must be between 8 and 128"})
@@ -184,6 +252,11 @@ type UUIDResponse struct {
 }
 
 func (h *EntropyHandler) UUID(c *gin.Context) {
+	if h.health.Suspect() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "entropy source failed health test"})
+		return
+	}
+
 	bytes := h.pool.GetBytes(16)
 	if bytes == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "insufficient entropy"})
@@ -206,6 +279,31 @@ func (h *EntropyHandler) UUID(c *gin.Context) {
 	})
 }
 
+type EntropyHealthResponse struct {
+	Suspect    bool    `json:"suspect"`
+	MinEntropy float64 `json:"min_entropy_estimate"`
+}
+
+// Health reports the SP 800-90B continuous health test state: whether the
+// pool is currently fail-closed and the rolling min-entropy estimate.
+func (h *EntropyHandler) Health(c *gin.Context) {
+	c.JSON(http.StatusOK, EntropyHealthResponse{
+		Suspect:    h.health.Suspect(),
+		MinEntropy: h.health.MinEntropyEstimate(),
+	})
+}
+
+// bytesToSamples adapts an already-extracted byte stream to the []int
+// shape Pool.Add expects, so extracted output can be pooled the same way
+// raw samples would be.
+func bytesToSamples(b []byte) []int {
+	samples := make([]int, len(b))
+	for i, v := range b {
+		samples[i] = int(v)
+	}
+	return samples
+}
+
 func encodeIntSlice(ints []int) []byte {
 	buf := make([]byte, len(ints)*4)
 	for i, v := range ints {
@@ -1,30 +1,53 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
+	"photon-entropy/config"
 	"photon-entropy/game"
+	"photon-entropy/logging"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Defaults used when a caller doesn't specify settings, shared by CreateGame
+// and Matchmake's fallback game creation. TimeLimit and MaxStrikes instead
+// come from the live config.Watcher (config.GameConfig's game.
+// default_time_limit / game.default_strikes yaml keys) so an operator can
+// tune them without a redeploy; these are the defaults that aren't exposed
+// there.
+const (
+	defaultModulesCount = 5
+	defaultPlayMode     = game.ModeSequential
+	defaultStageSize    = 2
+	defaultDifficulty   = game.DifficultyNormal
+)
+
 // GameHandler handles game-related HTTP requests
 type GameHandler struct {
 	engine *game.Engine
+	config *config.Watcher
 }
 
 // NewGameHandler creates a new game handler
-func NewGameHandler(engine *game.Engine) *GameHandler {
+func NewGameHandler(engine *game.Engine, cfg *config.Watcher) *GameHandler {
 	return &GameHandler{
 		engine: engine,
+		config: cfg,
 	}
 }
 
 // CreateGameRequest represents a request to create a new game
 type CreateGameRequest struct {
-	TimeLimit    int `json:"time_limit"`
-	ModulesCount int `json:"modules_count"`
-	MaxStrikes   int `json:"max_strikes"`
+	TimeLimit    int             `json:"time_limit"`
+	ModulesCount int             `json:"modules_count"`
+	MaxStrikes   int             `json:"max_strikes"`
+	PlayMode     game.PlayMode   `json:"play_mode"`
+	StageSize    int             `json:"stage_size"`
+	Difficulty   game.Difficulty `json:"difficulty"`
+	PlayerToken  string          `json:"player_token"`
 }
 
 // CreateGameResponse represents the response after creating a game
@@ -43,13 +66,28 @@ func (h *GameHandler) CreateGame(c *gin.Context) {
 
 	// Set defaults
 	if req.TimeLimit <= 0 {
-		req.TimeLimit = 300 // 5 minutes
+		req.TimeLimit = h.config.Get().Game.DefaultTimeLimit
 	}
 	if req.ModulesCount <= 0 {
-		req.ModulesCount = 5
+		req.ModulesCount = defaultModulesCount
 	}
 	if req.MaxStrikes <= 0 {
-		req.MaxStrikes = 3
+		req.MaxStrikes = h.config.Get().Game.DefaultStrikes
+	}
+	switch req.PlayMode {
+	case game.ModeSequential, game.ModeParallel, game.ModeStages:
+		// valid, keep as requested
+	default:
+		req.PlayMode = defaultPlayMode
+	}
+	if req.StageSize <= 0 {
+		req.StageSize = defaultStageSize
+	}
+	switch req.Difficulty {
+	case game.DifficultyEasy, game.DifficultyNormal, game.DifficultyHard, game.DifficultyExpert:
+		// valid, keep as requested
+	default:
+		req.Difficulty = defaultDifficulty
 	}
 
 	// Validate
@@ -60,7 +98,7 @@ func (h *GameHandler) CreateGame(c *gin.Context) {
 		req.ModulesCount = 5
 	}
 
-	g, err := h.engine.CreateGame(req.TimeLimit, req.ModulesCount, req.MaxStrikes)
+	g, err := h.engine.CreateGame(req.TimeLimit, req.ModulesCount, req.MaxStrikes, req.PlayMode, req.StageSize, req.Difficulty, req.PlayerToken)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -109,6 +147,108 @@ func (h *GameHandler) JoinGame(c *gin.Context) {
 	})
 }
 
+// MatchmakeRequest represents a request to join any open game for a role
+type MatchmakeRequest struct {
+	Role        string `json:"role" binding:"required"`
+	PlayerToken string `json:"player_token"`
+}
+
+// Matchmake handles POST /api/v1/game/matchmake
+// It joins the oldest waiting game missing the requested role, or creates a
+// fresh default game if none is available, and returns the same payload as
+// JoinGame.
+func (h *GameHandler) Matchmake(c *gin.Context) {
+	var req MatchmakeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Role != "bomb" && req.Role != "expert" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'bomb' or 'expert'"})
+		return
+	}
+
+	gameCfg := h.config.Get().Game
+	g, err := h.engine.Matchmake(req.Role, gameCfg.DefaultTimeLimit, defaultModulesCount, gameCfg.DefaultStrikes, defaultPlayMode, defaultStageSize, defaultDifficulty, req.PlayerToken)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"game_id":          g.ID,
+		"code":             g.Code,
+		"state":            g.State,
+		"time_limit":       g.TimeLimit,
+		"modules_count":    g.ModulesCount,
+		"bomb_connected":   g.BombConnected,
+		"expert_connected": g.ExpertConnected,
+	})
+}
+
+// ReadyRequest represents a request to toggle a role's ready state
+type ReadyRequest struct {
+	GameID string `json:"game_id" binding:"required"`
+	Role   string `json:"role" binding:"required"`
+	Ready  bool   `json:"ready"`
+}
+
+// Ready handles POST /api/v1/game/ready. Both roles must be ready before
+// StartGame will accept the lobby.
+func (h *GameHandler) Ready(c *gin.Context) {
+	var req ReadyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Role != "bomb" && req.Role != "expert" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'bomb' or 'expert'"})
+		return
+	}
+
+	g, err := h.engine.SetReady(req.GameID, req.Role, req.Ready)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bomb_ready":   g.BombReady,
+		"expert_ready": g.ExpertReady,
+	})
+}
+
+// LeaveRequest represents a request to leave a game
+type LeaveRequest struct {
+	GameID string `json:"game_id" binding:"required"`
+	Role   string `json:"role" binding:"required"`
+}
+
+// Leave handles POST /api/v1/game/leave, freeing role's seat immediately so
+// someone else can take it. Unlike a dropped WebSocket, this takes effect
+// with no grace period.
+func (h *GameHandler) Leave(c *gin.Context) {
+	var req LeaveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Role != "bomb" && req.Role != "expert" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'bomb' or 'expert'"})
+		return
+	}
+
+	if _, err := h.engine.Leave(req.GameID, req.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "left"})
+}
+
 // StartGame handles POST /api/v1/game/start
 func (h *GameHandler) StartGame(c *gin.Context) {
 	gameID := c.Query("game_id")
@@ -161,9 +301,13 @@ func (h *GameHandler) GetGameState(c *gin.Context) {
 		"time_left":        g.TimeLeft,
 		"strikes":          g.Strikes,
 		"max_strikes":      g.MaxStrikes,
+		"play_mode":        g.PlayMode,
+		"difficulty":       g.Difficulty,
 		"modules":          h.sanitizeModules(g.Modules),
 		"bomb_connected":   g.BombConnected,
 		"expert_connected": g.ExpertConnected,
+		"bomb_ready":       g.BombReady,
+		"expert_ready":     g.ExpertReady,
 		"created_at":       g.CreatedAt,
 		"started_at":       g.StartedAt,
 		"ended_at":         g.EndedAt,
@@ -230,6 +374,18 @@ func (h *GameHandler) ProcessAction(c *gin.Context) {
 
 	result, err := h.engine.ProcessAction(req.GameID, req.ModuleID, req.Action, req.Value)
 	if err != nil {
+		logger := logging.WithGameID(logging.FromContext(c), req.GameID)
+		logger.Warn().
+			Err(err).
+			Str("module_id", req.ModuleID).
+			Str("action", req.Action).
+			Msg("process action failed")
+
+		if errors.Is(err, game.ErrActionRateLimited) {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -246,6 +402,247 @@ func (h *GameHandler) ProcessAction(c *gin.Context) {
 	})
 }
 
+// GetActions handles GET /api/v1/game/actions?game_id=&since=<seq>
+// It returns the sequential action log for a game, newer than since, so a
+// reconnecting client (or a post-mortem tool) can catch up on everything it
+// missed.
+func (h *GameHandler) GetActions(c *gin.Context) {
+	gameID := c.Query("game_id")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "game_id required"})
+		return
+	}
+
+	since, _ := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+
+	entries, err := h.engine.GetActionsSince(gameID, since)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"game_id": gameID,
+		"since":   since,
+		"actions": entries,
+	})
+}
+
+// GetAction handles GET /api/v1/game/actions/:seq?game_id=
+func (h *GameHandler) GetAction(c *gin.Context) {
+	gameID := c.Query("game_id")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "game_id required"})
+		return
+	}
+
+	seq, err := strconv.ParseInt(c.Param("seq"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid seq"})
+		return
+	}
+
+	entry, err := h.engine.GetAction(gameID, seq)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetReplay handles GET /api/v1/game/replay?game_id=
+// It returns the game's full event timeline as newline-delimited JSON, for
+// bug-report attachments or feeding into a Replayer.
+func (h *GameHandler) GetReplay(c *gin.Context) {
+	gameID := c.Query("game_id")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "game_id required"})
+		return
+	}
+
+	data, err := h.engine.ExportReplay(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/x-ndjson", data)
+}
+
+// GetReplayByID handles GET /api/v1/game/:id/replay. Unlike GetReplay, which
+// only covers games still held in memory, it falls back to the persisted
+// gzip export SetReplayDir wrote when the game ended, so a finished game's
+// timeline stays available after CleanupGame evicts it from memory.
+func (h *GameHandler) GetReplayByID(c *gin.Context) {
+	gameID := c.Param("id")
+
+	data, err := h.engine.ExportReplay(gameID)
+	if err != nil {
+		data, err = h.engine.LoadReplay(gameID)
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "replay not found"})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/x-ndjson", data)
+}
+
+// ReplayVerifyResponse reports whether replaying a game's own recorded
+// event log through a fresh game.Replayer reproduces the live game's
+// outcome - the determinism guarantee game/replay.go is built on.
+type ReplayVerifyResponse struct {
+	Deterministic   bool           `json:"deterministic"`
+	LiveState       game.GameState `json:"live_state"`
+	ReplayedState   game.GameState `json:"replayed_state"`
+	LiveStrikes     int            `json:"live_strikes"`
+	ReplayedStrikes int            `json:"replayed_strikes"`
+}
+
+// VerifyReplay handles GET /api/v1/game/replay/verify?game_id=. It's a
+// time-travel debugging aid: if a processModuleAction or RuleGenerator
+// change ever makes a replay diverge from what actually happened, this
+// flips to false for that game instead of the corruption going unnoticed
+// until a tournament result is disputed.
+func (h *GameHandler) VerifyReplay(c *gin.Context) {
+	gameID := c.Query("game_id")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "game_id required"})
+		return
+	}
+
+	g, ok := h.engine.GetGame(gameID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	}
+
+	replayed, err := h.engine.ReconstructGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ReplayVerifyResponse{
+		Deterministic:   g.State == replayed.State && g.Strikes == replayed.Strikes,
+		LiveState:       g.State,
+		ReplayedState:   replayed.State,
+		LiveStrikes:     g.Strikes,
+		ReplayedStrikes: replayed.Strikes,
+	})
+}
+
+// SpectateRequest represents a request to watch a game by its join code
+type SpectateRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Spectate handles POST /api/v1/game/spectate
+// It looks up the game by code so the caller can then open a WebSocket
+// connection with role=spectator to receive redacted module snapshots.
+func (h *GameHandler) Spectate(c *gin.Context) {
+	var req SpectateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	g, err := h.engine.JoinAsSpectator(req.Code)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"game_id":       g.ID,
+		"code":          g.Code,
+		"state":         g.State,
+		"time_limit":    g.TimeLimit,
+		"modules_count": g.ModulesCount,
+	})
+}
+
+// ListGamesResponse wraps the live game browser's summaries
+type ListGamesResponse struct {
+	Games []game.GameSummary `json:"games"`
+}
+
+// ListGames handles GET /api/v1/game/list, returning a summary of every
+// in-progress game for a spectator lobby browser.
+func (h *GameHandler) ListGames(c *gin.Context) {
+	c.JSON(http.StatusOK, ListGamesResponse{Games: h.engine.ListGames()})
+}
+
+// PlayerStatsResponse reports one client token's per-module-type attempt and
+// failure counts, as tracked by the adaptive difficulty profile store.
+type PlayerStatsResponse struct {
+	Token    string                  `json:"token"`
+	Attempts map[game.ModuleType]int `json:"attempts"`
+	Failures map[game.ModuleType]int `json:"failures"`
+}
+
+// GetPlayerStats handles GET /api/v1/game/player-stats?token=
+func (h *GameHandler) GetPlayerStats(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token required"})
+		return
+	}
+
+	profile := h.engine.GetPlayerStats(token)
+
+	c.JSON(http.StatusOK, PlayerStatsResponse{
+		Token:    profile.Token,
+		Attempts: profile.Attempts,
+		Failures: profile.Failures,
+	})
+}
+
+// GameHistoryListResponse wraps a page of persisted match summaries.
+type GameHistoryListResponse struct {
+	Games []game.GameHistoryRecord `json:"games"`
+}
+
+// GetGameHistoryList handles GET /api/v1/game/history?limit=&offset=
+// It returns the most recently created matches, newest first, from the
+// configured HistoryStore - including ones no longer held in memory.
+func (h *GameHandler) GetGameHistoryList(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	records, err := h.engine.ListGameHistory(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, GameHistoryListResponse{Games: records})
+}
+
+// GetGameHistoryDetail handles GET /api/v1/game/:id
+// It returns one match's full persisted history: its summary, every player
+// that joined, every module's final state, and its lifecycle event
+// timeline, for a post-mortem view that outlives the in-memory Engine.
+func (h *GameHandler) GetGameHistoryDetail(c *gin.Context) {
+	history, err := h.engine.GetGameHistory(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
 // sanitizeModules removes solutions from modules before sending to clients
 func (h *GameHandler) sanitizeModules(modules []game.Module) []game.Module {
 	sanitized := make([]game.Module, len(modules))
@@ -3,17 +3,17 @@ package handlers
 import (
 	"net/http"
 
-	"github.com/P5ina/photon-entropy/db/sqlc"
+	"github.com/P5ina/photon-entropy/db"
 	"github.com/P5ina/photon-entropy/entropy"
 	"github.com/gin-gonic/gin"
 )
 
 type StatsHandler struct {
-	queries *sqlc.Queries
+	queries *db.Queries
 	pool    *entropy.Pool
 }
 
-func NewStatsHandler(q *sqlc.Queries, p *entropy.Pool) *StatsHandler {
+func NewStatsHandler(q *db.Queries, p *entropy.Pool) *StatsHandler {
 	return &StatsHandler{
 		queries: q,
 		pool:    p,
@@ -2,6 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+
+	"photon-entropy/game"
+	"photon-entropy/metrics"
 
 	"github.com/P5ina/photon-entropy/ws"
 	"github.com/gin-gonic/gin"
@@ -17,22 +21,84 @@ var upgrader = websocket.Upgrader{
 }
 
 type WebSocketHandler struct {
-	hub *ws.Hub
+	hub    *ws.Hub
+	engine *game.Engine
 }
 
-func NewWebSocketHandler(hub *ws.Hub) *WebSocketHandler {
-	return &WebSocketHandler{hub: hub}
+func NewWebSocketHandler(hub *ws.Hub, engine *game.Engine) *WebSocketHandler {
+	return &WebSocketHandler{hub: hub, engine: engine}
 }
 
+// Handle upgrades the connection and subscribes the client to the global
+// room plus, if game_id is given, that game's room. A role of "bomb",
+// "expert", or "spectator" additionally subscribes the client to that
+// game's role sub-room, so manual/expert-only and spectator-only broadcasts
+// never reach a socket they aren't meant for.
+//
+// If last_seq is given along with game_id, missed module_action/strike/
+// module_solved events since that sequence number are replayed to this
+// client alone before it joins the live room, so a flaky mobile connection
+// doesn't lose events between disconnect and reconnect.
 func (h *WebSocketHandler) Handle(c *gin.Context) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		return
 	}
+	metrics.WSConnections.Inc()
+
+	gameID := c.Query("game_id")
+	role := c.Query("role")
 
 	client := ws.NewClient(h.hub, conn)
-	h.hub.Register(client)
+	client.Join(ws.RoomGlobal)
+	if gameID != "" {
+		if role == "bomb" || role == "expert" {
+			// If this role had an outstanding auto-pause timer from a prior
+			// drop, cancel it and tell the other side play can resume,
+			// rather than treating this as a brand new join.
+			h.engine.HandleReconnect(gameID, role)
+		}
+		if lastSeq, err := strconv.ParseInt(c.Query("last_seq"), 10, 64); err == nil {
+			h.replayMissedActions(client, gameID, lastSeq)
+		}
+		client.Join(ws.GameRoom(gameID))
+		if role == "bomb" || role == "expert" || role == "spectator" {
+			client.Join(ws.GameRoleRoom(gameID, role))
+		}
+	}
 
 	go client.WritePump()
-	go client.ReadPump()
+	go func() {
+		client.ReadPump()
+		metrics.WSDisconnections.Inc()
+		if gameID != "" && (role == "bomb" || role == "expert") {
+			h.engine.HandleDisconnect(gameID, role)
+		}
+	}()
+}
+
+// replayMissedActions sends every action log entry after lastSeq directly to
+// client, classifying each as a strike/module_solved/module_action message
+// the same way a live broadcast would have.
+func (h *WebSocketHandler) replayMissedActions(client *ws.Client, gameID string, lastSeq int64) {
+	entries, err := h.engine.GetActionsSince(gameID, lastSeq)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		msgType := ws.MessageTypeModuleAction
+		switch {
+		case entry.Result != nil && entry.Result.Strike:
+			msgType = ws.MessageTypeStrike
+		case entry.Result != nil && entry.Result.Solved:
+			msgType = ws.MessageTypeModuleSolved
+		}
+
+		client.Send(ws.Message{
+			Type:      msgType,
+			Data:      entry,
+			Timestamp: entry.Timestamp,
+		})
+	}
 }
@@ -0,0 +1,49 @@
+// Package logging provides the process-wide structured logger. Every log
+// line should go through L (or a child logger scoped with WithGameID) so
+// multi-game debugging can filter the whole server's output down to one
+// game_id, instead of grepping unstructured log.Printf text.
+package logging
+
+import (
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// L is the process-wide structured logger, writing newline-delimited JSON
+// to stdout with a timestamp on every line.
+var L = zerolog.New(os.Stdout).With().Timestamp().Logger()
+
+// contextKey is the gin.Context key a request-scoped logger is stored
+// under by middleware.RequestLogger.
+const contextKey = "logging.logger"
+
+// WithGameID returns a child of logger tagged with gameID, so every field
+// it already carries (e.g. request_id) survives alongside it.
+func WithGameID(logger zerolog.Logger, gameID string) zerolog.Logger {
+	if gameID == "" {
+		return logger
+	}
+	return logger.With().Str("game_id", gameID).Logger()
+}
+
+// FromContext returns the request-scoped logger middleware.RequestLogger
+// attached to c (already tagged with a request_id and, when known, a
+// game_id), or the package-wide default L if the middleware wasn't
+// installed on this route.
+func FromContext(c *gin.Context) zerolog.Logger {
+	if v, ok := c.Get(contextKey); ok {
+		if logger, ok := v.(zerolog.Logger); ok {
+			return logger
+		}
+	}
+	return L
+}
+
+// Set stores logger on c under the key FromContext reads back. It's
+// exported only for middleware.RequestLogger to call; handlers should use
+// FromContext, not Set.
+func Set(c *gin.Context, logger zerolog.Logger) {
+	c.Set(contextKey, logger)
+}
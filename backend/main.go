@@ -1,22 +1,30 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"embed"
-	"log"
+	"fmt"
+	"net/http/pprof"
 	"os"
 	"path/filepath"
 	"time"
 
 	"photon-entropy/config"
-	"photon-entropy/db/sqlc"
+	"photon-entropy/db"
+	"photon-entropy/entropy"
 	"photon-entropy/game"
 	"photon-entropy/handlers"
+	"photon-entropy/logging"
+	"photon-entropy/metrics"
+	"photon-entropy/middleware"
+	"photon-entropy/verifier"
 	"photon-entropy/ws"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/pressly/goose/v3"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	_ "modernc.org/sqlite"
 )
 
@@ -24,58 +32,135 @@ import (
 var embedMigrations embed.FS
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplayCLI(os.Args[2:])
+		return
+	}
+
 	godotenv.Load()
 
 	env := config.LoadEnv()
-	cfg, err := config.Load("config.yaml")
+	cfgWatcher, err := config.Load("config.yaml")
 	if err != nil {
-		log.Printf("Warning: Failed to load config.yaml, using defaults: %v", err)
-		cfg = config.DefaultConfig()
+		logging.L.Warn().Err(err).Msg("failed to load config.yaml, using defaults")
+		cfgWatcher = config.NewWatcher(config.DefaultConfig())
 	}
-	_ = cfg // Config available for future use
+	cfgWatcher.OnReloadError = func(err error) {
+		logging.L.Warn().Err(err).Msg("failed to reload config.yaml, keeping previous config")
+	}
+	cfgWatcher.WatchSIGHUP()
+	cfg := cfgWatcher.Get()
 
 	if err := os.MkdirAll(filepath.Dir(env.DatabasePath), 0755); err != nil {
-		log.Fatalf("Failed to create data directory: %v", err)
+		logging.L.Fatal().Err(err).Msg("failed to create data directory")
 	}
 
-	db, err := sql.Open("sqlite", env.DatabasePath)
+	sqlDB, err := sql.Open("sqlite", env.DatabasePath)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		logging.L.Fatal().Err(err).Msg("failed to open database")
 	}
-	defer db.Close()
+	defer sqlDB.Close()
 
 	goose.SetBaseFS(embedMigrations)
 	if err := goose.SetDialect("sqlite3"); err != nil {
-		log.Fatalf("Failed to set goose dialect: %v", err)
+		logging.L.Fatal().Err(err).Msg("failed to set goose dialect")
 	}
-	if err := goose.Up(db, "db/migrations"); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if err := goose.Up(sqlDB, "db/migrations"); err != nil {
+		logging.L.Fatal().Err(err).Msg("failed to run migrations")
 	}
 
-	_ = sqlc.New(db) // Keep for potential future DB usage
+	queries := db.New(sqlDB)
 
 	// Initialize WebSocket hub
 	hub := ws.NewHub()
+	if cfg.Redis.Enabled {
+		broker, err := ws.NewRedisBroker(env.RedisURL)
+		if err != nil {
+			logging.L.Warn().Err(err).Msg("failed to connect to Redis, falling back to in-memory broker")
+		} else {
+			hub.SetBroker(broker)
+			defer broker.Close()
+		}
+	}
 	go hub.Run()
 
-	// Initialize game engine
-	gameEngine := game.NewEngine()
+	// Initialize game engine. NewEngine defaults to an in-memory Store; swap
+	// in game.NewBoltStore/game.NewSQLiteStore via SetStore before Restore
+	// for crash-resilient games across restarts.
+	gameEngine := game.NewEngine(cfg.RateLimit.GameActionRPS, cfg.RateLimit.GameActionBurst)
+	if gameStore, err := newGameStore(cfg.Store); err != nil {
+		logging.L.Warn().Err(err).Str("backend", cfg.Store.Backend).Msg("failed to open configured game store, keeping in-memory store")
+	} else if gameStore != nil {
+		gameEngine.SetStore(gameStore)
+		if closer, ok := gameStore.(interface{ Close() error }); ok {
+			defer closer.Close()
+		}
+	}
+	if err := gameEngine.Restore(); err != nil {
+		logging.L.Warn().Err(err).Msg("failed to restore games from store")
+	}
+	gameEngine.SetReplayDir(cfg.Replay.Dir)
+
+	// Persist match history (games/players/modules/events) to the same
+	// SQLite file the schema above was just migrated into, so /game/history
+	// and /game/:id survive past a game's in-memory lifetime.
+	historyStore, err := game.NewSQLHistoryStore(env.DatabasePath)
+	if err != nil {
+		logging.L.Warn().Err(err).Msg("failed to open history store")
+	} else {
+		gameEngine.SetHistoryStore(historyStore)
+		defer historyStore.Close()
+	}
 
 	// Connect game events to WebSocket broadcasts
 	gameEngine.OnGameEvent = func(event game.GameEvent) {
+		eventLogger := logging.WithGameID(logging.L, event.GameID)
+		eventLogger.Debug().
+			Str("event_type", event.Type).
+			Str("module_id", event.ModuleID).
+			Msg("game event")
+
 		switch event.Type {
 		case game.EventGameCreated:
-			hub.BroadcastGameEvent(ws.MessageTypeGameCreated, map[string]any{
+			metrics.GamesCreated.Inc()
+			// Lobby-level: no game room has any subscribers yet, so this
+			// goes out on the global room for lobby/matchmaking browsers.
+			hub.BroadcastGlobalEvent(ws.MessageTypeGameCreated, map[string]any{
 				"game_id": event.GameID,
 				"data":    event.Data,
 			})
 		case game.EventPlayerJoined:
-			hub.BroadcastGameEvent(ws.MessageTypePlayerJoined, map[string]any{
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypePlayerJoined, map[string]any{
+				"game_id": event.GameID,
+				"data":    event.Data,
+			})
+		case game.EventPlayerReady:
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypePlayerReady, map[string]any{
+				"game_id": event.GameID,
+				"data":    event.Data,
+			})
+		case game.EventPlayerUnready:
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypePlayerUnready, map[string]any{
+				"game_id": event.GameID,
+				"data":    event.Data,
+			})
+		case game.EventPlayerLeft:
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypePlayerLeft, map[string]any{
+				"game_id": event.GameID,
+				"data":    event.Data,
+			})
+		case game.EventPlayerReconnected:
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypePlayerReconnected, map[string]any{
+				"game_id": event.GameID,
+				"data":    event.Data,
+			})
+		case game.EventGamePaused:
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypeGamePaused, map[string]any{
 				"game_id": event.GameID,
 				"data":    event.Data,
 			})
 		case game.EventGameStarted:
-			hub.BroadcastGameEvent(ws.MessageTypeGameStarted, map[string]any{
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypeGameStarted, map[string]any{
 				"game_id": event.GameID,
 				"data":    event.Data,
 			})
@@ -84,30 +169,91 @@ func main() {
 				hub.BroadcastTimerTick(event.GameID, timeLeft)
 			}
 		case game.EventModuleAction:
-			hub.BroadcastGameEvent(ws.MessageTypeModuleAction, map[string]any{
+			hub.BroadcastModuleAction(event.GameID, event.ModuleID, map[string]any{
 				"game_id":   event.GameID,
 				"module_id": event.ModuleID,
 				"data":      event.Data,
 			})
 		case game.EventModuleSolved:
-			hub.BroadcastModuleSolved(event.GameID, event.ModuleID)
+			metrics.ModulesSolved.Inc()
+			nextModuleID, _ := event.Data["next_module_id"].(string)
+			activeModuleIndex, _ := event.Data["active_module_index"].(int)
+			hub.BroadcastModuleSolved(event.GameID, event.ModuleID, nextModuleID, activeModuleIndex)
 		case game.EventStrike:
+			metrics.StrikesIssued.Inc()
 			strikes, _ := event.Data["strikes"].(int)
 			maxStrikes, _ := event.Data["max_strikes"].(int)
 			reason, _ := event.Data["reason"].(string)
 			hub.BroadcastStrike(event.GameID, event.ModuleID, reason, strikes, maxStrikes)
 		case game.EventGameWon:
+			metrics.GamesWon.Inc()
 			timeRemaining, _ := event.Data["time_remaining"].(int)
 			hub.BroadcastGameEnd(event.GameID, true, "all_modules_solved", timeRemaining)
 		case game.EventGameLost:
+			metrics.GamesLost.Inc()
 			reason, _ := event.Data["reason"].(string)
 			hub.BroadcastGameEnd(event.GameID, false, reason, 0)
+		case game.EventSpectatorJoined:
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypeSpectatorJoined, map[string]any{
+				"game_id": event.GameID,
+			})
+		case game.EventStageUnlocked:
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypeStageUnlocked, map[string]any{
+				"game_id": event.GameID,
+				"data":    event.Data,
+			})
+		case game.EventStabilityPhase:
+			hub.BroadcastGameEvent(event.GameID, ws.MessageTypeStabilityPhase, map[string]any{
+				"game_id":   event.GameID,
+				"module_id": event.ModuleID,
+				"data":      event.Data,
+			})
+		case game.EventSpectatorSnapshot:
+			// Redacted module state belongs only to spectators, never the
+			// bomb/expert room, so this goes out on the spectator sub-room.
+			hub.BroadcastRoleEvent(event.GameID, "spectator", ws.MessageTypeSpectatorSnapshot, map[string]any{
+				"game_id": event.GameID,
+				"data":    event.Data,
+			})
+		}
+	}
+
+	// Persist every processed action so GetActions/GetAction and WS replay
+	// survive a server restart, not just an in-memory Engine lifetime.
+	gameEngine.OnAction = func(entry game.ActionLogEntry) {
+		if _, err := queries.CreateGameAction(context.Background(), db.CreateGameActionParams{
+			GameID:    entry.GameID,
+			Seq:       entry.Seq,
+			ModuleID:  entry.ModuleID,
+			Action:    entry.Action,
+			Success:   entry.Result.Success,
+			Strike:    entry.Result.Strike,
+			Solved:    entry.Result.Solved,
+			CreatedAt: entry.Timestamp,
+		}); err != nil {
+			actionLogger := logging.WithGameID(logging.L, entry.GameID)
+			actionLogger.Error().Err(err).Msg("failed to persist game action")
 		}
 	}
 
+	// Initialize the entropy pipeline: a Fortuna-style Pool accumulates
+	// submitted samples, Verifier runs the SP 800-22 statistical test suite
+	// on each batch, HealthMonitor gates the pool with SP 800-90B continuous
+	// health tests, and Extractor debiases accepted batches before they're
+	// added to the pool.
+	entropyPool := entropy.NewPool(cfg.Entropy.MaxPoolSize, cfg.Entropy.SeedPath)
+	entropyVerifier := verifier.New()
+	entropyHealth := verifier.NewHealthMonitor(cfg.Entropy.MinEntropy)
+	entropyExtractor := entropy.NewExtractor(cfg.Entropy.ExtractorSeedPath, cfg.Entropy.ExtractorCompression)
+
 	// Initialize handlers
-	gameHandler := handlers.NewGameHandler(gameEngine)
-	wsHandler := handlers.NewWebSocketHandler(hub)
+	gameHandler := handlers.NewGameHandler(gameEngine, cfgWatcher)
+	wsHandler := handlers.NewWebSocketHandler(hub, gameEngine)
+	deviceHandler := handlers.NewDeviceHandler(queries, cfgWatcher, hub)
+	configHandler := handlers.NewConfigHandler(cfgWatcher)
+	entropyHandler := handlers.NewEntropyHandler(queries, entropyPool, entropyVerifier, entropyHealth, entropyExtractor, cfg, hub, deviceHandler.Sessions())
+	drbgHandler := handlers.NewDRBGHandler(entropyPool)
+	statsHandler := handlers.NewStatsHandler(queries, entropyPool)
 
 	if env.GinMode == "release" {
 		gin.SetMode(gin.ReleaseMode)
@@ -122,22 +268,108 @@ func main() {
 		})
 	})
 
+	// Prometheus scrape endpoint
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// pprof is only exposed outside gin.ReleaseMode, or when an operator
+	// explicitly opts in with ENABLE_PPROF=true, since it lets a caller
+	// dump goroutine stacks and heap profiles.
+	if env.GinMode != gin.ReleaseMode || env.EnablePprof {
+		registerPprofRoutes(r)
+	}
+
 	// WebSocket endpoint
 	r.GET("/ws", wsHandler.Handle)
 
 	api := r.Group("/api/v1")
+	api.Use(middleware.RequestLogger())
+	api.Use(middleware.Metrics())
+	api.Use(middleware.GlobalRateLimit(cfg.RateLimit.GlobalRPS, cfg.RateLimit.GlobalBurst))
 	{
 		// Game endpoints
 		api.POST("/game/create", gameHandler.CreateGame)
 		api.POST("/game/join", gameHandler.JoinGame)
+		api.POST("/game/matchmake", gameHandler.Matchmake)
+		api.POST("/game/ready", gameHandler.Ready)
+		api.POST("/game/leave", gameHandler.Leave)
 		api.POST("/game/start", gameHandler.StartGame)
 		api.GET("/game/state", gameHandler.GetGameState)
 		api.GET("/game/manual", gameHandler.GetManual)
 		api.POST("/game/action", gameHandler.ProcessAction)
+		api.GET("/game/actions", gameHandler.GetActions)
+		api.GET("/game/actions/:seq", gameHandler.GetAction)
+		api.POST("/game/spectate", gameHandler.Spectate)
+		api.GET("/game/list", gameHandler.ListGames)
+		api.GET("/game/replay", gameHandler.GetReplay)
+		api.GET("/game/replay/verify", gameHandler.VerifyReplay)
+		api.GET("/game/player-stats", gameHandler.GetPlayerStats)
+		api.GET("/game/history", gameHandler.GetGameHistoryList)
+		api.GET("/game/:id", gameHandler.GetGameHistoryDetail)
+		api.GET("/game/:id/replay", gameHandler.GetReplayByID)
+
+		// Admin endpoints
+		api.GET("/config", configHandler.GetConfig)
+
+		// Device endpoints
+		api.POST("/device/register", deviceHandler.RegisterDevice)
+		api.POST("/device/handshake", deviceHandler.Handshake)
+		api.POST("/device/status", deviceHandler.UpdateStatus)
+		api.GET("/device/status", deviceHandler.Status)
+		api.GET("/device/history", deviceHandler.History)
+
+		// Entropy endpoints
+		api.POST("/entropy/submit", entropyHandler.Submit)
+		api.GET("/entropy/random", entropyHandler.Random)
+		api.GET("/entropy/password", entropyHandler.Password)
+		api.GET("/entropy/uuid", entropyHandler.UUID)
+		api.GET("/entropy/health", entropyHandler.Health)
+
+		// DRBG endpoints
+		api.POST("/drbg/instantiate", drbgHandler.Instantiate)
+		api.POST("/drbg/:id/generate", drbgHandler.Generate)
+		api.POST("/drbg/:id/reseed", drbgHandler.Reseed)
+
+		// Stats endpoint
+		api.GET("/stats", statsHandler.Stats)
 	}
 
-	log.Printf("Starting Bomb Defusal server on %s", env.ServerAddress())
+	logging.L.Info().Str("address", env.ServerAddress()).Msg("starting Bomb Defusal server")
 	if err := r.Run(env.ServerAddress()); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		logging.L.Fatal().Err(err).Msg("failed to start server")
+	}
+}
+
+// registerPprofRoutes mounts the standard net/http/pprof handlers under
+// /debug/pprof as static routes (gin's router won't let a wildcard and
+// static siblings coexist), matching the paths operators already expect
+// from `go tool pprof http://host/debug/pprof/profile`.
+func registerPprofRoutes(r *gin.Engine) {
+	r.GET("/debug/pprof/", gin.WrapF(pprof.Index))
+	r.GET("/debug/pprof/cmdline", gin.WrapF(pprof.Cmdline))
+	r.GET("/debug/pprof/profile", gin.WrapF(pprof.Profile))
+	r.GET("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	r.POST("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	r.GET("/debug/pprof/trace", gin.WrapF(pprof.Trace))
+	r.GET("/debug/pprof/heap", gin.WrapH(pprof.Handler("heap")))
+	r.GET("/debug/pprof/goroutine", gin.WrapH(pprof.Handler("goroutine")))
+	r.GET("/debug/pprof/allocs", gin.WrapH(pprof.Handler("allocs")))
+	r.GET("/debug/pprof/block", gin.WrapH(pprof.Handler("block")))
+	r.GET("/debug/pprof/mutex", gin.WrapH(pprof.Handler("mutex")))
+	r.GET("/debug/pprof/threadcreate", gin.WrapH(pprof.Handler("threadcreate")))
+}
+
+// newGameStore opens the game.Store cfg selects. A "memory" backend returns
+// (nil, nil) so the caller leaves Engine on its default MemoryStore instead
+// of swapping in an equivalent one.
+func newGameStore(cfg config.StoreConfig) (game.Store, error) {
+	switch cfg.Backend {
+	case "", "memory":
+		return nil, nil
+	case "bolt":
+		return game.NewBoltStore(cfg.Path)
+	case "sqlite":
+		return game.NewSQLiteStore(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", cfg.Backend)
 	}
 }
@@ -0,0 +1,70 @@
+// Package metrics holds the process-wide Prometheus collectors for the game
+// and WebSocket subsystems. Collectors are registered at package init via
+// promauto, so importing this package and calling its exported vars is
+// enough to wire a metric up; main.go only needs to mount /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// GamesCreated counts every game.Engine.CreateGame/Matchmake call that
+	// produced a new game, including Matchmake's fallback creation.
+	GamesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "photon_games_created_total",
+		Help: "Total number of games created.",
+	})
+
+	// GamesWon and GamesLost count how a game's OnGameEvent dispatcher saw
+	// it end, mirroring the EventGameWon/EventGameLost split in main.go.
+	GamesWon = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "photon_games_won_total",
+		Help: "Total number of games that ended in a win.",
+	})
+	GamesLost = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "photon_games_lost_total",
+		Help: "Total number of games that ended in a loss.",
+	})
+
+	// StrikesIssued and ModulesSolved count individual in-game events, not
+	// game outcomes, so they keep incrementing throughout a single game.
+	StrikesIssued = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "photon_strikes_issued_total",
+		Help: "Total number of strikes issued across all games.",
+	})
+	ModulesSolved = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "photon_modules_solved_total",
+		Help: "Total number of modules solved across all games.",
+	})
+
+	// WSConnections and WSDisconnections count WebSocket lifecycle events
+	// from handlers.WebSocketHandler.Handle.
+	WSConnections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "photon_ws_connections_total",
+		Help: "Total number of WebSocket connections accepted.",
+	})
+	WSDisconnections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "photon_ws_disconnections_total",
+		Help: "Total number of WebSocket connections closed.",
+	})
+
+	// BroadcastLatency is the time a room broadcast spends queued on
+	// Hub.broadcast before ws.Hub.Run hands it to the Broker, labeled by
+	// message type so a slow event type doesn't hide behind frequent ones.
+	BroadcastLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "photon_broadcast_latency_seconds",
+		Help:    "Time a room broadcast spent queued before being published.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"message_type"})
+
+	// HTTPRequestDuration is recorded by middleware.Metrics for every
+	// /api/v1 request, labeled by route pattern (not the raw path, to keep
+	// cardinality bounded), method, and status code.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "photon_http_request_duration_seconds",
+		Help:    "HTTP request duration in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+)
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"photon-entropy/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records request duration and status for every request, labeled by
+// route pattern rather than the raw path so a game_id or device_id in the
+// URL doesn't blow up the metric's cardinality.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.HTTPRequestDuration.WithLabelValues(
+			route,
+			c.Request.Method,
+			strconv.Itoa(c.Writer.Status()),
+		).Observe(time.Since(start).Seconds())
+	}
+}
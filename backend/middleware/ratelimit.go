@@ -0,0 +1,25 @@
+// Package middleware holds Gin middleware shared across the API.
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// GlobalRateLimit throttles every request through a single shared token
+// bucket, so no client (or combination of clients) can overwhelm the API.
+// Hits return 429 with Retry-After so well-behaved clients can back off.
+func GlobalRateLimit(rps float64, burst int) gin.HandlerFunc {
+	limiter := rate.NewLimiter(rate.Limit(rps), burst)
+
+	return func(c *gin.Context) {
+		if !limiter.Allow() {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
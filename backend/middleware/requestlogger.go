@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"photon-entropy/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// RequestLogger attaches a request-scoped logger to the Gin context, tagged
+// with a generated request_id and, when present, the game_id from the
+// request's query string or JSON body. Handlers and the game engine's
+// callbacks pull it back out via logging.FromContext(c) so every log line
+// about a request can be filtered down to one game.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logging.L.With().Str("request_id", generateRequestID()).Logger()
+
+		if gameID := gameIDFromRequest(c); gameID != "" {
+			logger = logging.WithGameID(logger, gameID)
+		}
+
+		logging.Set(c, logger)
+		c.Next()
+	}
+}
+
+// gameIDFromRequest looks for a game ID on the query string first (GET
+// endpoints like /game/state), falling back to the JSON body (POST
+// endpoints like /game/action). ShouldBindBodyWith caches the raw body on
+// c, so this doesn't consume it for the handler's own ShouldBindJSON call.
+func gameIDFromRequest(c *gin.Context) string {
+	if id := c.Query("game_id"); id != "" {
+		return id
+	}
+
+	var probe struct {
+		GameID string `json:"game_id"`
+	}
+	if err := c.ShouldBindBodyWith(&probe, binding.JSON); err == nil {
+		return probe.GameID
+	}
+	return ""
+}
+
+// generateRequestID returns a short random hex string unique enough to
+// correlate one request's log lines, without the overhead of a real UUID
+// library.
+func generateRequestID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"photon-entropy/game"
+	"photon-entropy/handlers"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/P5ina/photon-entropy/ws"
+)
+
+// runReplayCLI implements the `photon-entropy replay <file>` subcommand. It
+// starts a standalone WebSocket hub (no database, no live game.Engine) and
+// re-broadcasts a previously exported replay file's events into it with the
+// same relative timing they were recorded with, so a frontend can be pointed
+// at --addr and exercised against a real game's event timeline without
+// replaying the game itself. It never touches the real server's hub or
+// config, so it's safe to run alongside a live instance on a different port.
+func runReplayCLI(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	addr := fs.String("addr", ":8090", "address to serve the replay WebSocket on")
+	speed := fs.Float64("speed", 1.0, "playback speed multiplier (2.0 = twice as fast)")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fmt.Fprintln(os.Stderr, "usage: photon-entropy replay [--addr=:8090] [--speed=1.0] <file.ndjson[.gz]>")
+		os.Exit(1)
+	}
+	path := fs.Arg(0)
+	if *speed <= 0 {
+		fmt.Fprintln(os.Stderr, "--speed must be positive")
+		os.Exit(1)
+	}
+
+	entries, err := loadReplayFile(path)
+	if err != nil {
+		log.Fatalf("replay: %v", err)
+	}
+	if len(entries) == 0 {
+		log.Fatalf("replay: %s contains no events", path)
+	}
+
+	hub := ws.NewHub()
+	go hub.Run()
+
+	engine := game.NewEngine(0, 0)
+	wsHandler := handlers.NewWebSocketHandler(hub, engine)
+
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.GET("/ws", wsHandler.Handle)
+
+	go func() {
+		log.Printf("replay: serving %s on %s (speed=%.2fx, %d events)", path, *addr, *speed, len(entries))
+		if err := r.Run(*addr); err != nil {
+			log.Fatalf("replay: server: %v", err)
+		}
+	}()
+
+	broadcastReplay(hub, entries, *speed)
+	log.Println("replay: playback finished, server still running for inspection")
+	select {}
+}
+
+// loadReplayFile reads path, transparently gunzipping it if it's gzip
+// compressed (on-disk exports from Engine.persistReplay are; a file fetched
+// from GET /api/v1/game/:id/replay is not), and decodes it as the
+// newline-delimited game.ReplayEntry stream ExportReplay produces.
+func loadReplayFile(path string) ([]game.ReplayEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if gz, err := gzip.NewReader(f); err == nil {
+		defer gz.Close()
+		r = gz
+	} else if _, seekErr := f.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+
+	var entries []game.ReplayEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry game.ReplayEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("decode entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// broadcastReplay pushes entries into hub's game room in order, sleeping
+// between each to reproduce its original OffsetSeconds spacing scaled by
+// speed.
+func broadcastReplay(hub *ws.Hub, entries []game.ReplayEntry, speed float64) {
+	start := time.Now()
+	for _, entry := range entries {
+		due := start.Add(time.Duration(entry.OffsetSeconds / speed * float64(time.Second)))
+		if d := time.Until(due); d > 0 {
+			time.Sleep(d)
+		}
+		hub.BroadcastGameEvent(entry.GameID, ws.MessageType(entry.Type), entry.Data)
+	}
+}
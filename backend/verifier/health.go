@@ -0,0 +1,223 @@
+package verifier
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	// healthAlphaExp defines the continuous health tests' false-alarm
+	// probability alpha = 2^-healthAlphaExp, as mandated by FIPS 140-2 /
+	// SP 800-90B.
+	healthAlphaExp = 30
+
+	// healthWindow is the Adaptive Proportion Test's window size W.
+	healthWindow = 512
+
+	// DefaultMinEntropy is the conservative per-sample min-entropy estimate
+	// (H, in bits) the continuous test cutoffs are derived from when no
+	// override is configured - only the 4 LSBs of each raw photon sample
+	// feed the pool, so 0.5 bits/sample is the assumed floor.
+	DefaultMinEntropy = 0.5
+)
+
+// HealthResult reports the outcome of running the continuous health tests
+// against one incoming sample batch.
+type HealthResult struct {
+	Passed     bool    `json:"passed"`
+	Reason     string  `json:"reason,omitempty"`
+	MinEntropy float64 `json:"min_entropy_estimate"`
+	Suspect    bool    `json:"suspect"`
+}
+
+// HealthMonitor runs the SP 800-90B Repetition Count Test and Adaptive
+// Proportion Test on every incoming sample batch before it reaches the
+// pool, and maintains a rolling min-entropy estimate via the "most common
+// value" estimator (SP 800-90B §6.3.1). Once either test fires, it fails
+// closed: Suspect() stays true, and output must be refused, until a
+// subsequent clean batch arrives.
+type HealthMonitor struct {
+	mu sync.Mutex
+
+	minEntropy       float64
+	repetitionCutoff int
+	proportionCutoff int
+
+	valueCounts   map[int]int
+	totalSamples  int
+	minEntropyEst float64
+
+	suspect bool
+}
+
+// NewHealthMonitor creates a monitor whose cutoffs are derived from the
+// given per-sample min-entropy estimate H. A non-positive H falls back to
+// DefaultMinEntropy.
+func NewHealthMonitor(minEntropy float64) *HealthMonitor {
+	if minEntropy <= 0 {
+		minEntropy = DefaultMinEntropy
+	}
+	return &HealthMonitor{
+		minEntropy:       minEntropy,
+		repetitionCutoff: repetitionCountCutoff(minEntropy),
+		proportionCutoff: adaptiveProportionCutoff(healthWindow, minEntropy),
+		valueCounts:      make(map[int]int),
+	}
+}
+
+// Check runs both continuous tests against samples and, if they pass,
+// folds the batch into the rolling min-entropy estimate. It updates and
+// returns the monitor's fail-closed Suspect state.
+func (m *HealthMonitor) Check(samples []int) HealthResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reason := ""
+	switch {
+	case !repetitionCountTest(samples, m.repetitionCutoff):
+		reason = "repetition count test failed"
+	case !adaptiveProportionTest(samples, healthWindow, m.proportionCutoff):
+		reason = "adaptive proportion test failed"
+	}
+
+	if reason != "" {
+		m.suspect = true
+		return HealthResult{Passed: false, Reason: reason, MinEntropy: m.minEntropyEst, Suspect: true}
+	}
+
+	m.updateMinEntropyLocked(samples)
+	m.suspect = false
+	return HealthResult{Passed: true, MinEntropy: m.minEntropyEst, Suspect: false}
+}
+
+// Suspect reports whether the most recent batch failed a health test and no
+// clean batch has arrived since.
+func (m *HealthMonitor) Suspect() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.suspect
+}
+
+// MinEntropyEstimate returns the current rolling min-entropy estimate, in
+// bits per sample.
+func (m *HealthMonitor) MinEntropyEstimate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.minEntropyEst
+}
+
+// repetitionCountTest fails if any value repeats more than cutoff times in
+// a row, per SP 800-90B §4.4.1.
+func repetitionCountTest(samples []int, cutoff int) bool {
+	run := 1
+	for i := 1; i < len(samples); i++ {
+		if samples[i] == samples[i-1] {
+			run++
+			if run > cutoff {
+				return false
+			}
+		} else {
+			run = 1
+		}
+	}
+	return true
+}
+
+// adaptiveProportionTest fails if, for any sample, its value recurs more
+// than cutoff times within the following window samples, per
+// SP 800-90B §4.4.2.
+func adaptiveProportionTest(samples []int, window, cutoff int) bool {
+	for i := 0; i < len(samples); i++ {
+		ref := samples[i]
+		end := i + window
+		if end > len(samples) {
+			end = len(samples)
+		}
+
+		count := 0
+		for j := i + 1; j < end; j++ {
+			if samples[j] == ref {
+				count++
+			}
+		}
+		if count > cutoff {
+			return false
+		}
+	}
+	return true
+}
+
+// updateMinEntropyLocked folds samples into the running value-frequency
+// table and recomputes H_min from the most-common-value estimator: p̂ is
+// the observed maximum proportion, bumped to a 99% upper confidence bound,
+// and H_min = -log2(p̂_upper).
+func (m *HealthMonitor) updateMinEntropyLocked(samples []int) {
+	for _, s := range samples {
+		m.valueCounts[s&0xFF]++
+		m.totalSamples++
+	}
+	if m.totalSamples < 2 {
+		return
+	}
+
+	maxCount := 0
+	for _, count := range m.valueCounts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	n := float64(m.totalSamples)
+	pHat := float64(maxCount) / n
+	pUpper := pHat + 2.576*math.Sqrt(pHat*(1-pHat)/(n-1))
+	if pUpper > 1 {
+		pUpper = 1
+	}
+
+	m.minEntropyEst = -math.Log2(pUpper)
+}
+
+// repetitionCountCutoff computes C = 1 + ceil(-log2(alpha)/H).
+func repetitionCountCutoff(h float64) int {
+	return 1 + int(math.Ceil(float64(healthAlphaExp)/h))
+}
+
+// adaptiveProportionCutoff finds the smallest c such that
+// P(X >= c) <= alpha for X ~ Binomial(window-1, 2^-h), the per-SP-800-90B
+// cutoff for the Adaptive Proportion Test's counting window.
+func adaptiveProportionCutoff(window int, h float64) int {
+	n := window - 1
+	p := math.Exp2(-h)
+	alpha := math.Exp2(-healthAlphaExp)
+
+	tail := 0.0
+	for c := n; c >= 0; c-- {
+		tail += binomialPMF(n, c, p)
+		if tail > alpha {
+			return c + 1
+		}
+	}
+	return 0
+}
+
+func binomialPMF(n, k int, p float64) float64 {
+	if p <= 0 {
+		if k == 0 {
+			return 1
+		}
+		return 0
+	}
+	if p >= 1 {
+		if k == n {
+			return 1
+		}
+		return 0
+	}
+
+	lgN1, _ := math.Lgamma(float64(n + 1))
+	lgK1, _ := math.Lgamma(float64(k + 1))
+	lgNK1, _ := math.Lgamma(float64(n - k + 1))
+	logCoeff := lgN1 - lgK1 - lgNK1
+
+	return math.Exp(logCoeff + float64(k)*math.Log(p) + float64(n-k)*math.Log(1-p))
+}
@@ -0,0 +1,88 @@
+package verifier
+
+import "testing"
+
+// TestRepetitionCountTestThreshold checks the repetition count test's
+// documented behavior: it fails the moment a run exceeds cutoff, and passes
+// a run that reaches exactly cutoff.
+func TestRepetitionCountTestThreshold(t *testing.T) {
+	cutoff := 3
+
+	atCutoff := []int{7, 7, 7, 1, 2, 3}
+	if !repetitionCountTest(atCutoff, cutoff) {
+		t.Errorf("repetitionCountTest with a run of exactly cutoff (%d) reported failure, want pass", cutoff)
+	}
+
+	overCutoff := []int{7, 7, 7, 7, 1, 2}
+	if repetitionCountTest(overCutoff, cutoff) {
+		t.Errorf("repetitionCountTest with a run exceeding cutoff (%d) reported pass, want failure", cutoff)
+	}
+}
+
+// TestAdaptiveProportionTestThreshold checks that the test fails once a
+// value recurs more than cutoff times within the following window, and
+// passes when it recurs exactly cutoff times.
+func TestAdaptiveProportionTestThreshold(t *testing.T) {
+	window := 5
+	cutoff := 2
+
+	// Value 9 recurs twice (indices 2, 4) within the 5-sample window
+	// starting at index 0 - exactly at cutoff.
+	atCutoff := []int{9, 1, 9, 2, 9}
+	if !adaptiveProportionTest(atCutoff, window, cutoff) {
+		t.Errorf("adaptiveProportionTest with recurrence exactly at cutoff (%d) reported failure, want pass", cutoff)
+	}
+
+	// Value 9 recurs three times within the window - over cutoff.
+	overCutoff := []int{9, 9, 9, 9, 1}
+	if adaptiveProportionTest(overCutoff, window, cutoff) {
+		t.Errorf("adaptiveProportionTest with recurrence over cutoff (%d) reported pass, want failure", cutoff)
+	}
+}
+
+// TestHealthMonitorFailsClosedThenRecovers checks HealthMonitor's
+// fail-closed contract: a batch that fails a continuous test marks Suspect
+// true, and a subsequent clean batch clears it.
+func TestHealthMonitorFailsClosedThenRecovers(t *testing.T) {
+	m := NewHealthMonitor(DefaultMinEntropy)
+
+	// A long run of the same value trips the repetition count test for any
+	// realistic cutoff derived from DefaultMinEntropy.
+	badBatch := make([]int, 200)
+	for i := range badBatch {
+		badBatch[i] = 42
+	}
+
+	result := m.Check(badBatch)
+	if result.Passed {
+		t.Fatal("Check(badBatch) reported Passed=true, want a repetition count failure")
+	}
+	if !m.Suspect() {
+		t.Fatal("Suspect() = false after a failed batch, want true")
+	}
+
+	goodBatch := make([]int, 200)
+	for i := range goodBatch {
+		goodBatch[i] = i % 251
+	}
+
+	result = m.Check(goodBatch)
+	if !result.Passed {
+		t.Fatalf("Check(goodBatch) reported Passed=false (reason: %q), want true", result.Reason)
+	}
+	if m.Suspect() {
+		t.Error("Suspect() = true after a clean batch, want false")
+	}
+}
+
+// TestNewHealthMonitorDefaultsNonPositiveEntropy checks that a non-positive
+// minEntropy falls back to DefaultMinEntropy rather than producing a
+// degenerate (zero or negative) cutoff.
+func TestNewHealthMonitorDefaultsNonPositiveEntropy(t *testing.T) {
+	m := NewHealthMonitor(0)
+	want := NewHealthMonitor(DefaultMinEntropy)
+
+	if m.repetitionCutoff != want.repetitionCutoff {
+		t.Errorf("repetitionCutoff for minEntropy=0 = %d, want %d (DefaultMinEntropy's cutoff)", m.repetitionCutoff, want.repetitionCutoff)
+	}
+}
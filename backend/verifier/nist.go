@@ -0,0 +1,406 @@
+package verifier
+
+import "math"
+
+// nistAlpha is the standard NIST SP 800-22 significance level: a test
+// passes when its p-value is at least this, i.e. the observed statistic
+// isn't rare enough to reject randomness at the 99% confidence level.
+const nistAlpha = 0.01
+
+// blockFrequencyBlockSize and longestRunBlockSize are both fixed at 128
+// bits, the NIST-recommended block size for sample counts in the range
+// this service typically sees per submission.
+const (
+	blockFrequencyBlockSize = 128
+	longestRunBlockSize     = 128
+)
+
+// longestRunM128Pi holds the NIST reference probabilities for each longest-
+// run-length class when M=128 (SP 800-22 §2.4, Table 2-4): runs of length
+// <=4, 5, 6, 7, 8, and >=9 respectively.
+var longestRunM128Pi = [6]float64{0.1174, 0.2430, 0.2493, 0.1752, 0.1027, 0.1124}
+
+// bitsFromSamples extracts each sample's 4 least-significant bits, matching
+// the bit width the rest of this package's heuristic tests already assume.
+func bitsFromSamples(samples []int) []int {
+	bits := make([]int, 0, len(samples)*4)
+	for _, sample := range samples {
+		for i := 0; i < 4; i++ {
+			bits = append(bits, (sample>>i)&1)
+		}
+	}
+	return bits
+}
+
+// monobitFrequency computes the NIST SP 800-22 §2.1 frequency (monobit)
+// statistic and p-value directly from a bitstream, so it can be checked
+// against the spec's published example vectors independent of how samples
+// get turned into bits.
+func monobitFrequency(bits []int) (sObs, p float64) {
+	n := len(bits)
+	if n == 0 {
+		return 0, 0
+	}
+
+	sum := 0
+	for _, b := range bits {
+		if b == 1 {
+			sum++
+		} else {
+			sum--
+		}
+	}
+
+	sObs = math.Abs(float64(sum)) / math.Sqrt(float64(n))
+	p = math.Erfc(sObs / math.Sqrt2)
+	return sObs, p
+}
+
+// MonobitFrequencyTest is the NIST SP 800-22 §2.1 frequency (monobit) test:
+// it checks that the proportion of ones and zeros is close to 1/2 across
+// the whole bitstream.
+func MonobitFrequencyTest(samples []int) TestResult {
+	bits := bitsFromSamples(samples)
+	if len(bits) == 0 {
+		return TestResult{Passed: false}
+	}
+
+	sObs, p := monobitFrequency(bits)
+	return TestResult{Passed: p >= nistAlpha, Value: sObs, PValue: p}
+}
+
+// blockFrequency computes the NIST SP 800-22 §2.2 block frequency
+// chi-square statistic and p-value for a given block size directly from a
+// bitstream.
+func blockFrequency(bits []int, m int) (chiSquare, p float64) {
+	numBlocks := len(bits) / m
+	if numBlocks == 0 {
+		return 0, 0
+	}
+
+	for i := 0; i < numBlocks; i++ {
+		ones := 0
+		for j := 0; j < m; j++ {
+			ones += bits[i*m+j]
+		}
+		pi := float64(ones) / float64(m)
+		chiSquare += (pi - 0.5) * (pi - 0.5)
+	}
+	chiSquare *= 4 * float64(m)
+
+	p = igamc(float64(numBlocks)/2, chiSquare/2)
+	return chiSquare, p
+}
+
+// BlockFrequencyTest is the NIST SP 800-22 §2.2 block frequency test: it
+// splits the bitstream into fixed-size blocks and checks that each block's
+// proportion of ones is also close to 1/2, catching local bias a whole-
+// stream average can hide.
+func BlockFrequencyTest(samples []int) TestResult {
+	bits := bitsFromSamples(samples)
+	if len(bits) < blockFrequencyBlockSize {
+		return TestResult{Passed: false}
+	}
+
+	chiSquare, p := blockFrequency(bits, blockFrequencyBlockSize)
+	return TestResult{Passed: p >= nistAlpha, Value: chiSquare, PValue: p}
+}
+
+// conditionedRuns computes the NIST SP 800-22 §2.3 runs statistic and
+// p-value directly from a bitstream. It's "conditioned" on the monobit
+// proportion first, since a biased sequence makes the run count itself
+// meaningless - NIST defines that case (ok=false) as an outright failure
+// rather than a test that can't be evaluated.
+func conditionedRuns(bits []int) (vObs float64, p float64, ok bool) {
+	n := len(bits)
+	if n < 2 {
+		return 0, 0, false
+	}
+
+	ones := 0
+	for _, b := range bits {
+		ones += b
+	}
+	pi := float64(ones) / float64(n)
+
+	tau := 2 / math.Sqrt(float64(n))
+	if math.Abs(pi-0.5) >= tau {
+		return 0, 0, false
+	}
+
+	v := 1
+	for k := 1; k < n; k++ {
+		if bits[k] != bits[k-1] {
+			v++
+		}
+	}
+
+	num := math.Abs(float64(v) - 2*float64(n)*pi*(1-pi))
+	den := 2 * math.Sqrt(2*float64(n)) * pi * (1 - pi)
+	return float64(v), math.Erfc(num / den), true
+}
+
+// ConditionedRunsTest is the NIST SP 800-22 §2.3 runs test: it checks that
+// the number of runs (uninterrupted sequences of the same bit) matches what
+// a random sequence with the observed bit proportion would produce.
+func ConditionedRunsTest(samples []int) TestResult {
+	vObs, p, ok := conditionedRuns(bitsFromSamples(samples))
+	if !ok {
+		return TestResult{Passed: false}
+	}
+	return TestResult{Passed: p >= nistAlpha, Value: vObs, PValue: p}
+}
+
+// longestRunOfOnes computes the NIST SP 800-22 §2.4 longest-run-of-ones
+// chi-square statistic and p-value for block size 128 directly from a
+// bitstream.
+func longestRunOfOnes(bits []int) (chiSquare, p float64) {
+	m := longestRunBlockSize
+	numBlocks := len(bits) / m
+	if numBlocks == 0 {
+		return 0, 0
+	}
+
+	var classCounts [6]int
+	for i := 0; i < numBlocks; i++ {
+		longest, current := 0, 0
+		for j := 0; j < m; j++ {
+			if bits[i*m+j] == 1 {
+				current++
+				if current > longest {
+					longest = current
+				}
+			} else {
+				current = 0
+			}
+		}
+
+		switch {
+		case longest <= 4:
+			classCounts[0]++
+		case longest == 5:
+			classCounts[1]++
+		case longest == 6:
+			classCounts[2]++
+		case longest == 7:
+			classCounts[3]++
+		case longest == 8:
+			classCounts[4]++
+		default:
+			classCounts[5]++
+		}
+	}
+
+	for i, count := range classCounts {
+		expected := float64(numBlocks) * longestRunM128Pi[i]
+		diff := float64(count) - expected
+		chiSquare += diff * diff / expected
+	}
+
+	p = igamc(2.5, chiSquare/2)
+	return chiSquare, p
+}
+
+// LongestRunOfOnesTest is the NIST SP 800-22 §2.4 longest-run-of-ones test
+// with a fixed block size of 128 bits: it compares the distribution of each
+// block's longest run of ones to the reference distribution in
+// longestRunM128Pi.
+func LongestRunOfOnesTest(samples []int) TestResult {
+	bits := bitsFromSamples(samples)
+	if len(bits) < longestRunBlockSize {
+		return TestResult{Passed: false}
+	}
+
+	chiSquare, p := longestRunOfOnes(bits)
+	return TestResult{Passed: p >= nistAlpha, Value: chiSquare, PValue: p}
+}
+
+// cumulativeSumsPValue computes the NIST SP 800-22 §2.13 cumulative sums
+// statistic and p-value, walking the bitstream forward or in reverse.
+func cumulativeSumsPValue(bits []int, forward bool) (float64, float64) {
+	n := len(bits)
+	if n == 0 {
+		return 0, 0
+	}
+
+	x := make([]int, n)
+	for i, b := range bits {
+		if b == 1 {
+			x[i] = 1
+		} else {
+			x[i] = -1
+		}
+	}
+	if !forward {
+		for i, j := 0, len(x)-1; i < j; i, j = i+1, j-1 {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sum, maxAbs := 0, 0
+	for _, v := range x {
+		sum += v
+		if abs := intAbs(sum); abs > maxAbs {
+			maxAbs = abs
+		}
+	}
+
+	z := float64(maxAbs)
+	if z == 0 {
+		return 0, 1
+	}
+	nf := float64(n)
+	sqrtN := math.Sqrt(nf)
+
+	end := math.Floor((nf/z - 1) / 4)
+
+	sum1 := 0.0
+	for k := math.Floor((-nf/z + 1) / 4); k <= end; k++ {
+		sum1 += standardNormalCDF((4*k+1)*z/sqrtN) - standardNormalCDF((4*k-1)*z/sqrtN)
+	}
+
+	sum2 := 0.0
+	for k := math.Floor((-nf/z - 3) / 4); k <= end; k++ {
+		sum2 += standardNormalCDF((4*k+3)*z/sqrtN) - standardNormalCDF((4*k+1)*z/sqrtN)
+	}
+
+	return z, 1 - sum1 + sum2
+}
+
+func intAbs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// CumulativeSumsForwardTest is the NIST SP 800-22 §2.13 cumulative sums
+// test, walking the bitstream forward.
+func CumulativeSumsForwardTest(samples []int) TestResult {
+	z, p := cumulativeSumsPValue(bitsFromSamples(samples), true)
+	return TestResult{Passed: p >= nistAlpha, Value: z, PValue: p}
+}
+
+// CumulativeSumsBackwardTest is the same test walking the bitstream in
+// reverse, which can surface bias the forward pass misses near the end of
+// the stream.
+func CumulativeSumsBackwardTest(samples []int) TestResult {
+	z, p := cumulativeSumsPValue(bitsFromSamples(samples), false)
+	return TestResult{Passed: p >= nistAlpha, Value: z, PValue: p}
+}
+
+// ApproximateEntropyTest is the NIST SP 800-22 §2.12 approximate entropy
+// test for pattern length m: it compares the frequency of all overlapping
+// m-bit and (m+1)-bit patterns to what's expected from a random sequence.
+// The battery runs this at m=2 and m=3.
+func ApproximateEntropyTest(samples []int, m int) TestResult {
+	bits := bitsFromSamples(samples)
+	n := len(bits)
+	if n == 0 || m < 1 {
+		return TestResult{Passed: false}
+	}
+
+	apEn := apEnPhi(bits, m) - apEnPhi(bits, m+1)
+	chiSquare := 2 * float64(n) * (math.Ln2 - apEn)
+	p := igamc(math.Pow(2, float64(m-1)), chiSquare/2)
+
+	return TestResult{Passed: p >= nistAlpha, Value: apEn, PValue: p}
+}
+
+// apEnPhi computes phi(m) for ApproximateEntropyTest. The bitstream is
+// treated as circular (wrapping its first m-1 bits onto the end) so every
+// position has a full m-bit pattern to sample, per the NIST reference
+// algorithm.
+func apEnPhi(bits []int, m int) float64 {
+	n := len(bits)
+	extended := make([]int, n+m-1)
+	copy(extended, bits)
+	copy(extended[n:], bits[:m-1])
+
+	counts := make(map[string]int)
+	pattern := make([]byte, m)
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			if extended[i+j] == 1 {
+				pattern[j] = '1'
+			} else {
+				pattern[j] = '0'
+			}
+		}
+		counts[string(pattern)]++
+	}
+
+	phi := 0.0
+	for _, count := range counts {
+		freq := float64(count) / float64(n)
+		phi += freq * math.Log(freq)
+	}
+	return phi
+}
+
+// standardNormalCDF is the standard normal cumulative distribution
+// function, used by the cumulative sums test's p-value formula.
+func standardNormalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// igamc is the regularized upper incomplete gamma function Q(a, x), used to
+// convert a chi-square statistic with a given degrees of freedom into a
+// p-value. Implemented via the series/continued-fraction split from
+// Numerical Recipes, since Go's math package doesn't expose one directly.
+func igamc(a, x float64) float64 {
+	if x <= 0 || a <= 0 {
+		return 1
+	}
+	if x < a+1 {
+		return 1 - igamSeries(a, x)
+	}
+	return igamContinuedFraction(a, x)
+}
+
+func igamSeries(a, x float64) float64 {
+	lgam, _ := math.Lgamma(a)
+	ap := a
+	sum := 1.0 / a
+	del := sum
+	for n := 1; n < 200; n++ {
+		ap++
+		del *= x / ap
+		sum += del
+		if math.Abs(del) < math.Abs(sum)*1e-12 {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lgam)
+}
+
+func igamContinuedFraction(a, x float64) float64 {
+	const tiny = 1e-30
+	lgam, _ := math.Lgamma(a)
+
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+
+	for i := 1; i < 200; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < 1e-12 {
+			break
+		}
+	}
+
+	return math.Exp(-x+a*math.Log(x)-lgam) * h
+}
@@ -0,0 +1,107 @@
+package verifier
+
+import (
+	"math"
+	"testing"
+)
+
+// parseBits turns a string of '0'/'1' characters into the []int bit slice
+// the NIST battery's internal functions operate on.
+func parseBits(s string) []int {
+	bits := make([]int, len(s))
+	for i, c := range s {
+		if c == '1' {
+			bits[i] = 1
+		}
+	}
+	return bits
+}
+
+func approxEqual(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+// TestMonobitFrequency checks the n=10 example from NIST SP 800-22 §2.1.8:
+// ε = 1011010101 gives Sobs = 0.632455... and P-value = 0.527089.
+func TestMonobitFrequency(t *testing.T) {
+	bits := parseBits("1011010101")
+	sObs, p := monobitFrequency(bits)
+
+	if !approxEqual(sObs, 0.632455, 1e-5) {
+		t.Errorf("sObs = %v, want ~0.632455", sObs)
+	}
+	if !approxEqual(p, 0.527089, 1e-5) {
+		t.Errorf("p-value = %v, want ~0.527089", p)
+	}
+}
+
+// TestConditionedRuns checks the n=10 example from NIST SP 800-22 §2.3.8:
+// ε = 1001101011 gives Vn(obs) = 7 and P-value = 0.147232.
+func TestConditionedRuns(t *testing.T) {
+	bits := parseBits("1001101011")
+	vObs, p, ok := conditionedRuns(bits)
+
+	if !ok {
+		t.Fatal("conditionedRuns reported prerequisite frequency test failure, want ok")
+	}
+	if vObs != 7 {
+		t.Errorf("vObs = %v, want 7", vObs)
+	}
+	if !approxEqual(p, 0.147232, 1e-5) {
+		t.Errorf("p-value = %v, want ~0.147232", p)
+	}
+}
+
+// TestConditionedRunsFailsPrerequisite checks that a heavily biased
+// sequence is rejected by the frequency prerequisite rather than scored.
+func TestConditionedRunsFailsPrerequisite(t *testing.T) {
+	bits := parseBits("1111111111111111111111111111110")
+	if _, _, ok := conditionedRuns(bits); ok {
+		t.Error("conditionedRuns should fail the monobit prerequisite on a heavily biased sequence")
+	}
+}
+
+// TestApproximateEntropyPeriodicSequence checks ApEn(1) on a perfectly
+// periodic 2-bit-period sequence ("1010"): both the 1-bit and 2-bit
+// overlapping pattern distributions are exactly balanced (2 of each
+// pattern out of 4 windows), so phi(1) and phi(2) are equal and ApEn is
+// exactly zero - the minimum a sequence with any irregularity can reach.
+func TestApproximateEntropyPeriodicSequence(t *testing.T) {
+	bits := parseBits("1010")
+	apEn := apEnPhi(bits, 1) - apEnPhi(bits, 2)
+
+	if !approxEqual(apEn, 0, 1e-9) {
+		t.Errorf("ApEn(1) = %v, want 0 for a perfectly periodic sequence", apEn)
+	}
+}
+
+// TestIgamcBoundaries sanity-checks the incomplete gamma helper at values
+// with known closed forms, since the statistical tests all depend on it
+// for their p-values.
+func TestIgamcBoundaries(t *testing.T) {
+	if got := igamc(1, 0); got != 1 {
+		t.Errorf("igamc(1, 0) = %v, want 1", got)
+	}
+
+	// Q(1, x) = e^-x for the exponential distribution's survival function.
+	got := igamc(1, 1)
+	want := math.Exp(-1)
+	if !approxEqual(got, want, 1e-6) {
+		t.Errorf("igamc(1, 1) = %v, want ~%v", got, want)
+	}
+}
+
+// TestCumulativeSumsUnitExcursionForAlternatingRun checks that a perfectly
+// alternating sequence never drifts more than one step from zero, so z=1
+// and the p-value stays positive.
+func TestCumulativeSumsUnitExcursionForAlternatingRun(t *testing.T) {
+	bits := parseBits("1010101010")
+	z, p := cumulativeSumsPValue(bits, true)
+
+	if z != 1 {
+		t.Errorf("z = %v, want 1 for a single-step alternating walk", z)
+	}
+	if p <= 0 {
+		t.Errorf("p-value = %v, want > 0", p)
+	}
+}
@@ -7,6 +7,7 @@ import (
 type TestResult struct {
 	Passed bool    `json:"passed"`
 	Value  float64 `json:"value"`
+	PValue float64 `json:"p_value,omitempty"`
 }
 
 // FrequencyTest checks if the ratio of 1s to 0s in the LSBs is balanced (45-55%)
@@ -1,10 +1,21 @@
 package verifier
 
+// Tests holds the per-test results from a single Verify call: the original
+// four quick heuristics, plus the NIST SP 800-22 statistical test battery.
 type Tests struct {
 	Frequency TestResult `json:"frequency"`
 	Runs      TestResult `json:"runs"`
 	ChiSquare TestResult `json:"chi_square"`
 	Variance  TestResult `json:"variance"`
+
+	MonobitFrequency       TestResult `json:"monobit_frequency"`
+	BlockFrequency         TestResult `json:"block_frequency"`
+	ConditionedRuns        TestResult `json:"conditioned_runs"`
+	LongestRun             TestResult `json:"longest_run"`
+	CumulativeSumsForward  TestResult `json:"cumulative_sums_forward"`
+	CumulativeSumsBackward TestResult `json:"cumulative_sums_backward"`
+	ApproxEntropyM2        TestResult `json:"approximate_entropy_m2"`
+	ApproxEntropyM3        TestResult `json:"approximate_entropy_m3"`
 }
 
 type VerifyResult struct {
@@ -18,30 +29,58 @@ func New() *Verifier {
 	return &Verifier{}
 }
 
+// legacyTestWeight and nistTestWeight control each test's share of the
+// composite quality score. The NIST battery carries more weight since it
+// has an actual statistical basis, while the original four are kept as
+// cheap early heuristics.
+const (
+	legacyTestWeight = 1.0
+	nistTestWeight   = 2.0
+)
+
 func (v *Verifier) Verify(samples []int) VerifyResult {
 	tests := Tests{
 		Frequency: FrequencyTest(samples),
 		Runs:      RunsTest(samples),
 		ChiSquare: ChiSquareTest(samples),
 		Variance:  VarianceTest(samples),
-	}
 
-	passed := 0
-	if tests.Frequency.Passed {
-		passed++
+		MonobitFrequency:       MonobitFrequencyTest(samples),
+		BlockFrequency:         BlockFrequencyTest(samples),
+		ConditionedRuns:        ConditionedRunsTest(samples),
+		LongestRun:             LongestRunOfOnesTest(samples),
+		CumulativeSumsForward:  CumulativeSumsForwardTest(samples),
+		CumulativeSumsBackward: CumulativeSumsBackwardTest(samples),
+		ApproxEntropyM2:        ApproximateEntropyTest(samples, 2),
+		ApproxEntropyM3:        ApproximateEntropyTest(samples, 3),
 	}
-	if tests.Runs.Passed {
-		passed++
-	}
-	if tests.ChiSquare.Passed {
-		passed++
-	}
-	if tests.Variance.Passed {
-		passed++
+
+	weighted, total := 0.0, 0.0
+	for _, weighedTest := range []struct {
+		result TestResult
+		weight float64
+	}{
+		{tests.Frequency, legacyTestWeight},
+		{tests.Runs, legacyTestWeight},
+		{tests.ChiSquare, legacyTestWeight},
+		{tests.Variance, legacyTestWeight},
+		{tests.MonobitFrequency, nistTestWeight},
+		{tests.BlockFrequency, nistTestWeight},
+		{tests.ConditionedRuns, nistTestWeight},
+		{tests.LongestRun, nistTestWeight},
+		{tests.CumulativeSumsForward, nistTestWeight},
+		{tests.CumulativeSumsBackward, nistTestWeight},
+		{tests.ApproxEntropyM2, nistTestWeight},
+		{tests.ApproxEntropyM3, nistTestWeight},
+	} {
+		total += weighedTest.weight
+		if weighedTest.result.Passed {
+			weighted += weighedTest.weight
+		}
 	}
 
 	return VerifyResult{
-		Quality: float64(passed) / 4.0,
+		Quality: weighted / total,
 		Tests:   tests,
 	}
 }
@@ -0,0 +1,85 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisChannel is the single Redis pub/sub channel every photon-entropy
+// instance publishes Hub broadcasts to and subscribes from. The Room a
+// message belongs to travels inside the payload rather than as a separate
+// channel per room, since Redis pub/sub channels aren't cheap to create one
+// per game at scale.
+const RedisChannel = "photon-entropy:broadcast"
+
+// redisEnvelope carries a Room alongside its already-marshaled message so a
+// subscriber can hand it straight to deliverLocal without knowing anything
+// about the publisher's internal room map.
+type redisEnvelope struct {
+	Room    Room   `json:"room"`
+	Message []byte `json:"message"`
+}
+
+// RedisBroker fans Hub broadcasts out across every photon-entropy instance
+// subscribed to the same Redis server, so WebSocket clients connected to
+// different pods behind a load balancer still receive the same game's
+// events. Wire it in with Hub.SetBroker before calling Hub.Run.
+type RedisBroker struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+}
+
+// NewRedisBroker connects to redisURL (as accepted by redis.ParseURL) and
+// subscribes to RedisChannel.
+func NewRedisBroker(redisURL string) (*RedisBroker, error) {
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+
+	return &RedisBroker{
+		client: client,
+		pubsub: client.Subscribe(context.Background(), RedisChannel),
+	}, nil
+}
+
+// Publish marshals room and message into a redisEnvelope and publishes it to
+// RedisChannel, so every subscribed instance (including this one) delivers
+// it to its own local clients via Subscribe's fn.
+func (b *RedisBroker) Publish(room Room, message []byte) error {
+	data, err := json.Marshal(redisEnvelope{Room: room, Message: message})
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(context.Background(), RedisChannel, data).Err()
+}
+
+// Subscribe starts a goroutine that delivers every RedisChannel message
+// (published by any instance) to fn, so Hub's local room fan-out runs the
+// same way regardless of which instance actually published the event.
+func (b *RedisBroker) Subscribe(fn func(room Room, message []byte)) {
+	ch := b.pubsub.Channel()
+	go func() {
+		for msg := range ch {
+			var env redisEnvelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				continue
+			}
+			fn(env.Room, env.Message)
+		}
+	}()
+}
+
+// Close unsubscribes and releases the underlying Redis connection.
+func (b *RedisBroker) Close() error {
+	_ = b.pubsub.Close()
+	return b.client.Close()
+}
@@ -0,0 +1,130 @@
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = (pongWait * 9) / 10
+)
+
+// Client represents a single WebSocket connection subscribed to one or more rooms.
+type Client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	send chan []byte
+
+	mu    sync.RWMutex
+	rooms map[Room]bool
+}
+
+// NewClient creates a client wrapping a raw WebSocket connection.
+func NewClient(hub *Hub, conn *websocket.Conn) *Client {
+	return &Client{
+		hub:   hub,
+		conn:  conn,
+		send:  make(chan []byte, 256),
+		rooms: make(map[Room]bool),
+	}
+}
+
+// Join subscribes the client to a room, registering it with the hub.
+func (c *Client) Join(room Room) {
+	c.mu.Lock()
+	c.rooms[room] = true
+	c.mu.Unlock()
+	c.hub.Register(c, room)
+}
+
+// Leave unsubscribes the client from a single room.
+func (c *Client) Leave(room Room) {
+	c.mu.Lock()
+	delete(c.rooms, room)
+	c.mu.Unlock()
+	c.hub.Unregister(c, room)
+}
+
+// Send delivers msg to this client only, bypassing room broadcast. Used to
+// replay missed events to a single reconnecting client.
+func (c *Client) Send(msg Message) {
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now().UTC()
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
+}
+
+// Rooms returns the rooms this client currently belongs to.
+func (c *Client) Rooms() []Room {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rooms := make([]Room, 0, len(c.rooms))
+	for r := range c.rooms {
+		rooms = append(rooms, r)
+	}
+	return rooms
+}
+
+// ReadPump pumps inbound messages off the socket. The connection currently
+// has no client->server message types, so this just keeps the connection
+// alive and detects disconnects, leaving every joined room on exit.
+func (c *Client) ReadPump() {
+	defer func() {
+		for _, room := range c.Rooms() {
+			c.hub.Unregister(c, room)
+		}
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// WritePump pumps outbound messages to the socket and keeps it alive with pings.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -2,30 +2,114 @@ package ws
 
 import (
 	"encoding/json"
+	"fmt"
 	"sync"
 	"time"
+
+	"photon-entropy/logging"
+	"photon-entropy/metrics"
 )
 
 type MessageType string
 
 const (
 	// Game events
-	MessageTypeGameCreated  MessageType = "game_created"
-	MessageTypePlayerJoined MessageType = "player_joined"
-	MessageTypeGameStarted  MessageType = "game_started"
-	MessageTypeTimerTick    MessageType = "timer_tick"
-	MessageTypeModuleAction MessageType = "module_action"
-	MessageTypeModuleSolved MessageType = "module_solved"
-	MessageTypeStrike       MessageType = "strike"
-	MessageTypeGameWon      MessageType = "game_won"
-	MessageTypeGameLost     MessageType = "game_lost"
-	MessageTypeGameState    MessageType = "game_state"
-	MessageTypeMagnetState  MessageType = "magnet_state"
+	MessageTypeGameCreated       MessageType = "game_created"
+	MessageTypePlayerJoined      MessageType = "player_joined"
+	MessageTypePlayerReady       MessageType = "player_ready"
+	MessageTypePlayerUnready     MessageType = "player_unready"
+	MessageTypePlayerLeft        MessageType = "player_left"
+	MessageTypePlayerReconnected MessageType = "player_reconnected"
+	MessageTypeGameStarted       MessageType = "game_started"
+	MessageTypeGamePaused        MessageType = "game_paused"
+	MessageTypeTimerTick         MessageType = "timer_tick"
+	MessageTypeModuleAction      MessageType = "module_action"
+	MessageTypeModuleSolved      MessageType = "module_solved"
+	MessageTypeStrike            MessageType = "strike"
+	MessageTypeGameWon           MessageType = "game_won"
+	MessageTypeGameLost          MessageType = "game_lost"
+	MessageTypeGameState         MessageType = "game_state"
+	MessageTypeMagnetState       MessageType = "magnet_state"
 
 	// Device events
 	MessageTypeDeviceUpdate MessageType = "device_update"
+
+	// Entropy events
+	MessageTypeEntropyHealth MessageType = "entropy_health"
+
+	// Spectator events
+	MessageTypeSpectatorJoined   MessageType = "spectator_joined"
+	MessageTypeSpectatorSnapshot MessageType = "spectator_snapshot"
+
+	// Stage-based play mode events
+	MessageTypeStageUnlocked MessageType = "stage_unlocked"
+
+	// Stability module events
+	MessageTypeStabilityPhase MessageType = "stability_phase"
 )
 
+// Broker delivers room broadcasts across process boundaries, so that
+// multiple photon-entropy instances behind a load balancer still fan out
+// the same game's events to every client connected to any of them. Hub
+// calls Publish for every broadcast and registers its own local room
+// fan-out as the Subscribe callback, so MemoryBroker (single process) and
+// RedisBroker (multi-process) are interchangeable without Hub's room logic
+// knowing the difference.
+type Broker interface {
+	// Publish delivers message to every subscriber of room, including this
+	// process's own Subscribe callback.
+	Publish(room Room, message []byte) error
+
+	// Subscribe registers fn to run for every message Published to any
+	// room. Hub calls it once, before Run starts consuming broadcasts.
+	Subscribe(fn func(room Room, message []byte))
+}
+
+// MemoryBroker is the default Broker: it delivers straight back to its own
+// Subscribe callback, so a single photon-entropy instance behaves exactly
+// as it did before Broker existed. Call Hub.SetBroker with a RedisBroker
+// before Run for cross-instance delivery.
+type MemoryBroker struct {
+	fn func(room Room, message []byte)
+}
+
+// NewMemoryBroker creates a MemoryBroker with no subscriber yet; Hub
+// supplies one via Subscribe before Run starts.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{}
+}
+
+func (b *MemoryBroker) Publish(room Room, message []byte) error {
+	if b.fn != nil {
+		b.fn(room, message)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(fn func(room Room, message []byte)) {
+	b.fn = fn
+}
+
+// Room identifies a set of clients that should receive the same broadcasts.
+// Clients can belong to several rooms at once (e.g. a game room plus a
+// role-specific sub-room).
+type Room string
+
+// RoomGlobal carries device updates and lobby-level events that aren't tied
+// to any single game.
+const RoomGlobal Room = "global"
+
+// GameRoom is the room shared by every client (bomb + expert) in a game.
+func GameRoom(gameID string) Room {
+	return Room(fmt.Sprintf("game:%s", gameID))
+}
+
+// GameRoleRoom is a room scoped to one role within a game, used for payloads
+// (e.g. the manual) that must never reach the other role's socket.
+func GameRoleRoom(gameID, role string) Room {
+	return Room(fmt.Sprintf("game:%s:%s", gameID, role))
+}
+
 type Message struct {
 	Type      MessageType `json:"type"`
 	Data      any         `json:"data"`
@@ -39,73 +123,123 @@ type DeviceUpdateData struct {
 	IsTooBright bool      `json:"is_too_bright"`
 }
 
+type roomMembership struct {
+	client *Client
+	room   Room
+}
+
+type roomBroadcast struct {
+	room     Room
+	message  []byte
+	msgType  MessageType
+	queuedAt time.Time
+}
+
+// Hub fans out messages to clients grouped by Room, so that events from one
+// game never leak onto another game's (or role's) socket.
 type Hub struct {
-	clients    map[*Client]bool
-	broadcast  chan []byte
-	register   chan *Client
-	unregister chan *Client
-	mu         sync.RWMutex
+	rooms map[Room]map[*Client]bool
+
+	broadcast  chan roomBroadcast
+	register   chan roomMembership
+	unregister chan roomMembership
+
+	// broker publishes every broadcast and delivers it back to deliverLocal,
+	// so swapping in a RedisBroker is the only change needed to fan out
+	// across multiple instances. Defaults to a MemoryBroker.
+	broker Broker
+
+	mu sync.RWMutex
 }
 
 func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+	h := &Hub{
+		rooms:      make(map[Room]map[*Client]bool),
+		broadcast:  make(chan roomBroadcast, 256),
+		register:   make(chan roomMembership),
+		unregister: make(chan roomMembership),
+	}
+	h.SetBroker(NewMemoryBroker())
+	return h
+}
+
+// SetBroker swaps in a different Broker (e.g. a RedisBroker), re-subscribing
+// deliverLocal so every room fan-out still runs through it. Call this before
+// Run starts consuming broadcasts.
+func (h *Hub) SetBroker(b Broker) {
+	h.broker = b
+	h.broker.Subscribe(h.deliverLocal)
+}
+
+// deliverLocal fans message out to every client this instance has
+// subscribed to room, regardless of which instance actually published it.
+func (h *Hub) deliverLocal(room Room, message []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.rooms[room] {
+		select {
+		case client.send <- message:
+		default:
+			close(client.send)
+			delete(h.rooms[room], client)
+		}
 	}
 }
 
 func (h *Hub) Run() {
 	for {
 		select {
-		case client := <-h.register:
+		case m := <-h.register:
 			h.mu.Lock()
-			h.clients[client] = true
+			if h.rooms[m.room] == nil {
+				h.rooms[m.room] = make(map[*Client]bool)
+			}
+			h.rooms[m.room][m.client] = true
 			h.mu.Unlock()
 
-		case client := <-h.unregister:
+		case m := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+			if clients, ok := h.rooms[m.room]; ok {
+				delete(clients, m.client)
+				if len(clients) == 0 {
+					delete(h.rooms, m.room)
+				}
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+		case rb := <-h.broadcast:
+			metrics.BroadcastLatency.WithLabelValues(string(rb.msgType)).Observe(time.Since(rb.queuedAt).Seconds())
+			_ = h.broker.Publish(rb.room, rb.message)
 		}
 	}
 }
 
-func (h *Hub) Register(client *Client) {
-	h.register <- client
+// Register subscribes client to room. A client can be registered to several
+// rooms at once; each must be unregistered independently.
+func (h *Hub) Register(client *Client, room Room) {
+	h.register <- roomMembership{client: client, room: room}
 }
 
-func (h *Hub) Unregister(client *Client) {
-	h.unregister <- client
+// Unregister removes client from room. If this leaves the client with no
+// send channel consumers it will be garbage collected once its goroutines
+// observe the closed channel.
+func (h *Hub) Unregister(client *Client, room Room) {
+	h.unregister <- roomMembership{client: client, room: room}
 }
 
-func (h *Hub) Broadcast(msg Message) {
+// Broadcast sends msg to every client subscribed to room.
+func (h *Hub) Broadcast(room Room, msg Message) {
 	msg.Timestamp = time.Now().UTC()
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return
 	}
-	h.broadcast <- data
+	h.broadcast <- roomBroadcast{room: room, message: data, msgType: msg.Type, queuedAt: time.Now()}
 }
 
+// BroadcastDeviceUpdate announces a physical device's status on the global room.
 func (h *Hub) BroadcastDeviceUpdate(deviceID string, isOnline bool, lastSeen time.Time, isTooBright bool) {
-	h.Broadcast(Message{
+	h.Broadcast(RoomGlobal, Message{
 		Type: MessageTypeDeviceUpdate,
 		Data: DeviceUpdateData{
 			DeviceID:    deviceID,
@@ -116,20 +250,65 @@ func (h *Hub) BroadcastDeviceUpdate(deviceID string, isOnline bool, lastSeen tim
 	})
 }
 
+// EntropyHealthData reports the SP 800-90B continuous health test state
+// after processing a submitted sample batch.
+type EntropyHealthData struct {
+	Suspect    bool    `json:"suspect"`
+	MinEntropy float64 `json:"min_entropy_estimate"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// BroadcastEntropyHealth announces the entropy pool's health test state on
+// the global room.
+func (h *Hub) BroadcastEntropyHealth(suspect bool, minEntropy float64, reason string) {
+	h.Broadcast(RoomGlobal, Message{
+		Type: MessageTypeEntropyHealth,
+		Data: EntropyHealthData{
+			Suspect:    suspect,
+			MinEntropy: minEntropy,
+			Reason:     reason,
+		},
+	})
+}
+
+// ClientCount returns the number of distinct (room, client) subscriptions
+// currently tracked. A client in two rooms counts twice.
 func (h *Hub) ClientCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	return len(h.clients)
+	count := 0
+	for _, clients := range h.rooms {
+		count += len(clients)
+	}
+	return count
 }
 
 // Game-related broadcast methods
 
-// BroadcastGameEvent sends a game event to all connected clients
-func (h *Hub) BroadcastGameEvent(eventType MessageType, data any) {
-	h.Broadcast(Message{
-		Type: eventType,
-		Data: data,
-	})
+// BroadcastGlobalEvent sends a lobby-level event (not tied to one game) to
+// every client on the global room.
+func (h *Hub) BroadcastGlobalEvent(eventType MessageType, data any) {
+	h.Broadcast(RoomGlobal, Message{Type: eventType, Data: data})
+}
+
+// BroadcastGameEvent sends a game event to every client (bomb + expert)
+// subscribed to that game's room.
+func (h *Hub) BroadcastGameEvent(gameID string, eventType MessageType, data any) {
+	logger := logging.WithGameID(logging.L, gameID)
+	logger.Debug().Str("message_type", string(eventType)).Msg("broadcast game event")
+	h.Broadcast(GameRoom(gameID), Message{Type: eventType, Data: data})
+}
+
+// BroadcastRoleEvent sends an event to only one role's sub-room within a
+// game, e.g. manual contents or other expert/bomb-only payloads that must
+// never reach the other client even if sanitization were to fail upstream.
+func (h *Hub) BroadcastRoleEvent(gameID, role string, eventType MessageType, data any) {
+	logger := logging.WithGameID(logging.L, gameID)
+	logger.Debug().
+		Str("message_type", string(eventType)).
+		Str("role", role).
+		Msg("broadcast role event")
+	h.Broadcast(GameRoleRoom(gameID, role), Message{Type: eventType, Data: data})
 }
 
 // GameStateData represents game state for WebSocket broadcast
@@ -148,9 +327,9 @@ type GameEventData struct {
 	Data     any    `json:"data,omitempty"`
 }
 
-// BroadcastGameState sends current game state
+// BroadcastGameState sends current game state to the game's room
 func (h *Hub) BroadcastGameState(gameID, state string, timeLeft, strikes, maxStrikes int) {
-	h.Broadcast(Message{
+	h.Broadcast(GameRoom(gameID), Message{
 		Type: MessageTypeGameState,
 		Data: GameStateData{
 			GameID:     gameID,
@@ -162,9 +341,9 @@ func (h *Hub) BroadcastGameState(gameID, state string, timeLeft, strikes, maxStr
 	})
 }
 
-// BroadcastTimerTick sends timer update
+// BroadcastTimerTick sends a timer update to the game's room
 func (h *Hub) BroadcastTimerTick(gameID string, timeLeft int) {
-	h.Broadcast(Message{
+	h.Broadcast(GameRoom(gameID), Message{
 		Type: MessageTypeTimerTick,
 		Data: map[string]any{
 			"game_id":   gameID,
@@ -173,9 +352,9 @@ func (h *Hub) BroadcastTimerTick(gameID string, timeLeft int) {
 	})
 }
 
-// BroadcastStrike sends strike notification
+// BroadcastStrike sends a strike notification to the game's room
 func (h *Hub) BroadcastStrike(gameID, moduleID, reason string, strikes, maxStrikes int) {
-	h.Broadcast(Message{
+	h.Broadcast(GameRoom(gameID), Message{
 		Type: MessageTypeStrike,
 		Data: map[string]any{
 			"game_id":     gameID,
@@ -187,9 +366,9 @@ func (h *Hub) BroadcastStrike(gameID, moduleID, reason string, strikes, maxStrik
 	})
 }
 
-// BroadcastModuleSolved sends module solved notification
+// BroadcastModuleSolved sends a module solved notification to the game's room
 func (h *Hub) BroadcastModuleSolved(gameID, moduleID, nextModuleID string, activeModuleIndex int) {
-	h.Broadcast(Message{
+	h.Broadcast(GameRoom(gameID), Message{
 		Type: MessageTypeModuleSolved,
 		Data: map[string]any{
 			"game_id":             gameID,
@@ -200,13 +379,24 @@ func (h *Hub) BroadcastModuleSolved(gameID, moduleID, nextModuleID string, activ
 	})
 }
 
-// BroadcastGameEnd sends game over notification
+// BroadcastModuleAction sends a module action outcome to the game's room.
+// Payloads here must already be sanitized of solutions by the caller;
+// role-restricted data (e.g. the manual) should go through
+// BroadcastRoleEvent instead.
+func (h *Hub) BroadcastModuleAction(gameID, moduleID string, data any) {
+	h.Broadcast(GameRoom(gameID), Message{
+		Type: MessageTypeModuleAction,
+		Data: data,
+	})
+}
+
+// BroadcastGameEnd sends a game over notification to the game's room
 func (h *Hub) BroadcastGameEnd(gameID string, won bool, reason string, timeRemaining int) {
 	msgType := MessageTypeGameLost
 	if won {
 		msgType = MessageTypeGameWon
 	}
-	h.Broadcast(Message{
+	h.Broadcast(GameRoom(gameID), Message{
 		Type: msgType,
 		Data: map[string]any{
 			"game_id":        gameID,